@@ -1,9 +1,17 @@
 package api
 
 import (
+	"go_casbin/internal/controller/account"
+	"go_casbin/internal/controller/authz"
+	"go_casbin/internal/controller/policy"
 	"go_casbin/internal/controller/workFlow"
 	"go_casbin/internal/logger"
+	casbinMiddleware "go_casbin/internal/middleware/casbin"
+	jwtMiddleware "go_casbin/internal/middleware/jwt"
 	"go_casbin/internal/middleware/response"
+	authzService "go_casbin/internal/service/authz"
+	"go_casbin/pkg/casbin"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -75,14 +83,62 @@ func RegisterRoutes(r *gin.Engine) {
 		})
 		
 		workFlowController := workFlow.NewWorkFlowController()
-		v1.POST("/workFlow/create", workFlowController.CreateWorkFlow)//创建工作流模版
-		v1.GET("/workFlow/get", workFlowController.GetWorkFlow)//获取工作流模版
-		v1.GET("/workFlow/getList", workFlowController.GetWorkFlowList)//获取工作流模版列表
-		v1.POST("/workFlow/update", workFlowController.UpdateWorkFlow)//更新工作流模版
-		v1.POST("/workFlow/createInstance", workFlowController.CreateWorkFlowInstance)//创建工作流实例
-		v1.GET("/workFlow/getInstance", workFlowController.GetWorkFlowInstance)//获取工作流实例
-		v1.GET("/workFlow/getInstanceList", workFlowController.GetWorkFlowInstanceList)//获取工作流实例列表
-		v1.POST("/workFlow/deleteInstance", workFlowController.DeleteWorkFlowInstance)//删除工作流实例
-		v1.POST("/workFlow/approveInstance", workFlowController.ApproveWorkFlowInstance)//审批工作流实例
+		handle(v1, http.MethodPost, "/workFlow/create", "创建工作流模版", workFlowController.CreateWorkFlow)
+		handle(v1, http.MethodGet, "/workFlow/get", "获取工作流模版", workFlowController.GetWorkFlow)
+		handle(v1, http.MethodGet, "/workFlow/getList", "获取工作流模版列表", workFlowController.GetWorkFlowList)
+		handle(v1, http.MethodPost, "/workFlow/update", "更新工作流模版", workFlowController.UpdateWorkFlow)
+		handle(v1, http.MethodPost, "/workFlow/createInstance", "创建工作流实例", jwtMiddleware.JWTAuth(), casbinMiddleware.DataScope(), workFlowController.CreateWorkFlowInstance)//发起人、租户、部门取自token
+		handle(v1, http.MethodGet, "/workFlow/getInstance", "获取工作流实例", jwtMiddleware.JWTAuth(), casbinMiddleware.DataScope(), workFlowController.GetWorkFlowInstance)//不在数据范围内时按不存在处理
+		handle(v1, http.MethodGet, "/workFlow/getInstanceList", "获取工作流实例列表", jwtMiddleware.JWTAuth(), casbinMiddleware.DataScope(), workFlowController.GetWorkFlowInstanceList)//按数据范围过滤
+		handle(v1, http.MethodPost, "/workFlow/deleteInstance", "删除工作流实例", jwtMiddleware.JWTAuth(), casbinMiddleware.DataScope(), workFlowController.DeleteWorkFlowInstance)//不在数据范围内时按不存在处理
+		handle(v1, http.MethodPost, "/workFlow/approveInstance", "审批工作流实例", jwtMiddleware.JWTAuth(), casbinMiddleware.CasbinAuth(), casbinMiddleware.RequireRelation(authzService.ResourceWorkflowInstance, "id", casbin.RelationApprover), workFlowController.ApproveWorkFlowInstance)//需要审批接口的权限且只有当前步骤的审批人可以审批
+
+		// 账户接口：手机号、邮箱受字段权限保护(p4, 主体, account#phone, read/write)，无读权限时脱敏返回
+		casbinMiddleware.RegisterFields("account",
+			casbinMiddleware.FieldRule{Field: "phone", Mask: casbinMiddleware.MaskMiddle(3, 4)},
+			casbinMiddleware.FieldRule{Field: "email", Mask: casbinMiddleware.MaskEmail},
+		)
+		accountController := account.NewAccountController()
+		accounts := v1.Group("/accounts", jwtMiddleware.JWTAuth(), casbinMiddleware.CasbinAuth(), casbinMiddleware.FieldPermission("account"))
+		handle(accounts, http.MethodGet, "/:id", "获取账户", accountController.GetAccount)
+		handle(accounts, http.MethodPut, "/:id", "更新账户", casbinMiddleware.RequireRelation(authzService.ResourceAccount, "id", casbin.RelationOwner), accountController.UpdateAccount)//只能更新本人的账户
+
+		// 当前账户的权限列表，只需要登录
+		authzController := authz.NewAuthzController()
+		handle(v1, http.MethodGet, "/me/permissions", "当前账户的权限列表", jwtMiddleware.JWTAuth(), authzController.MyPermissions)//返回可执行的(obj, act)，可按prefix过滤
+
+		// 策略管理接口，需要登录并通过权限校验，鉴权模式可通过middleware.casbinGroups.admin配置
+		admin := v1.Group("", jwtMiddleware.JWTAuth(), casbinMiddleware.CasbinAuthGroup("admin"))
+		policyController := policy.NewPolicyController()
+		handle(admin, http.MethodGet, "/policies", "分页查询策略", policyController.ListPolicies)
+		handle(admin, http.MethodPost, "/policies", "添加策略", policyController.AddPolicy)
+		handle(admin, http.MethodPost, "/policies/batch", "批量添加策略", policyController.AddPolicies)
+		handle(admin, http.MethodDelete, "/policies", "删除策略", policyController.RemovePolicy)
+		handle(admin, http.MethodGet, "/policies/export", "导出策略", policyController.ExportPolicies)
+		handle(admin, http.MethodGet, "/policies/lint", "检查重复、孤立、被覆盖的策略", policyController.LintPolicies)
+		handle(admin, http.MethodPost, "/policies/import", "导入策略", policyController.ImportPolicies)
+		handle(admin, http.MethodGet, "/policies/snapshots", "分页查询策略快照", policyController.ListSnapshots)
+		handle(admin, http.MethodPost, "/policies/snapshots", "保存当前策略为快照", policyController.CreateSnapshot)
+		handle(admin, http.MethodGet, "/policies/snapshots/diff", "对比两个快照", policyController.DiffSnapshots)
+		handle(admin, http.MethodPost, "/policies/snapshots/:id/rollback", "回滚到指定快照", policyController.RollbackSnapshot)
+		handle(admin, http.MethodGet, "/data-scopes", "获取数据范围策略", policyController.ListDataScopes)
+		handle(admin, http.MethodPost, "/data-scopes", "设置数据范围", policyController.SetDataScope)
+		handle(admin, http.MethodDelete, "/data-scopes", "删除数据范围", policyController.RemoveDataScope)
+		handle(admin, http.MethodGet, "/field-policies", "获取字段权限策略", policyController.ListFieldPolicies)
+		handle(admin, http.MethodPost, "/field-policies", "授予字段权限", policyController.AddFieldPolicy)
+		handle(admin, http.MethodDelete, "/field-policies", "收回字段权限", policyController.RemoveFieldPolicy)
+		handle(admin, http.MethodGet, "/roles", "获取所有角色", policyController.ListRoles)
+		handle(admin, http.MethodPost, "/roles/assign", "给用户分配角色", policyController.AssignRole)
+		handle(admin, http.MethodPost, "/roles/unassign", "移除用户的角色", policyController.UnassignRole)
+		handle(admin, http.MethodGet, "/roles/:role/users", "获取角色下的用户", policyController.ListRoleUsers)
+		handle(admin, http.MethodGet, "/role-grants", "分页查询限时角色授权", policyController.ListRoleGrants)
+		handle(admin, http.MethodPost, "/role-grants", "创建限时角色授权", policyController.GrantRole)
+		handle(admin, http.MethodPost, "/role-grants/:id/revoke", "撤销限时角色授权", policyController.RevokeRoleGrant)
+		handle(admin, http.MethodGet, "/authz/cache/stats", "鉴权缓存命中统计", policyController.CacheStats)
+		handle(admin, http.MethodPost, "/authz/explain", "解释鉴权结果", authzController.Explain)
+		handle(admin, http.MethodPost, "/authz/dry-run", "预演策略变更", authzController.DryRun)
+		handle(admin, http.MethodGet, "/authz/shadow/stats", "影子模式鉴权统计", authzController.ShadowStats)
+		handle(admin, http.MethodGet, "/permissions", "获取权限目录", authzController.ListPermissions)//由路由表生成
+		handle(admin, http.MethodGet, "/permissions/validate", "校验策略是否指向存在的路由", authzController.ValidatePermissions)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"go_casbin/internal/logger"
+	"go_casbin/pkg/casbin"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiPrefix 分组取该前缀之后的第一段路径
+const apiPrefix = "/api/v1/"
+
+// routeDescriptions 路由说明，键为"方法 路径"，由handle在注册路由时登记；未登记的路由以权限名作为说明
+var routeDescriptions = make(map[string]string)
+
+// handle 注册路由并登记说明，说明与路由写在一起，新增路由时不会遗漏
+func handle(group *gin.RouterGroup, method, relativePath, description string, handlers ...gin.HandlerFunc) {
+	group.Handle(method, relativePath, handlers...)
+	routeDescriptions[method+" "+path.Join(group.BasePath(), relativePath)] = description
+}
+
+// BuildPermissionCatalog 由路由表生成权限目录，并校验已有策略，指向不存在路由的策略记录告警
+func BuildPermissionCatalog(r *gin.Engine) []casbin.CatalogIssue {
+	routes := r.Routes()
+	permissions := make([]casbin.Permission, 0, len(routes))
+	for _, route := range routes {
+		permission := casbin.Permission{
+			Name:   permissionName(route),
+			Method: route.Method,
+			Path:   route.Path,
+			Group:  permissionGroup(route.Path),
+		}
+		permission.Description = routeDescriptions[route.Method+" "+route.Path]
+		if permission.Description == "" {
+			permission.Description = permission.Name
+		}
+		permissions = append(permissions, permission)
+	}
+	catalog := casbin.GetCatalog()
+	catalog.Set(permissions)
+
+	enforcer := casbin.GetCasbinInstance()
+	if enforcer == nil {
+		return nil
+	}
+	issues, err := enforcer.ValidateAgainstCatalog(catalog)
+	if err != nil {
+		logger.ErrorWithErr("校验策略失败", err)
+		return nil
+	}
+	for _, issue := range issues {
+		logger.Warn("策略指向的路由不存在", logger.Field("rule", issue.Rule), logger.String("reason", issue.Reason))
+	}
+	logger.Info("权限目录生成完成", logger.Int("permissions", len(permissions)), logger.Int("issues", len(issues)))
+	return issues
+}
+
+// permissionName 由处理函数推导权限名：包名.方法名；匿名函数使用"方法 路径"
+func permissionName(route gin.RouteInfo) string {
+	name := route.Handler[strings.LastIndex(route.Handler, "/")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	if strings.Contains(name, ".func") {
+		return route.Method + " " + route.Path
+	}
+	// 去掉接收者类型，如policy.PolicyController.ListPolicies -> policy.ListPolicies
+	parts := strings.Split(name, ".")
+	return parts[0] + "." + parts[len(parts)-1]
+}
+
+// permissionGroup 取/api/v1之后的第一段路径作为分组，其他路由归入system
+func permissionGroup(path string) string {
+	if !strings.HasPrefix(path, apiPrefix) {
+		return "system"
+	}
+	group := strings.TrimPrefix(path, apiPrefix)
+	if i := strings.Index(group, "/"); i >= 0 {
+		group = group[:i]
+	}
+	return group
+}
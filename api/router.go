@@ -6,6 +6,7 @@ import (
 	"go_casbin/internal/middleware"
 	errorhandler "go_casbin/internal/middleware/error"
 	"go_casbin/internal/middleware/response"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -31,7 +32,7 @@ func InitRouter() *gin.Engine {
 	}
 
 	// 健康检查接口
-	r.GET("/health", func(c *gin.Context) {
+	handle(&r.RouterGroup, http.MethodGet, "/health", "健康检查", func(c *gin.Context) {
 		response.Success(c, gin.H{
 			"status":  "ok",
 			"service": config.ViperConfig.Service.Name,
@@ -42,7 +43,8 @@ func InitRouter() *gin.Engine {
 			},
 		})
 	})
-	RegisterRoutes(r) //挂载API
+	RegisterRoutes(r)         //挂载API
+	BuildPermissionCatalog(r) //由路由表生成权限目录并校验策略
 
 	// 注册404和405错误处理（必须在所有路由注册完成后）
 	r.NoRoute(errorhandler.NoRoute())
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go_casbin/internal/config"
+	"go_casbin/internal/service"
+	"go_casbin/pkg/casbin"
+	"os"
+)
+
+// runCommand 执行子命令并返回退出码，init中已完成配置、数据库和Casbin的初始化
+//
+//	reconcile-roles [-repair]  对比account_roles与Casbin g策略，-repair时以account_roles为准修复
+//	validate-model [path]      用现有策略校验模型文件，默认校验配置中的modelPath
+//	lint-policy                检查重复、孤立、被覆盖的策略
+func runCommand(args []string) int {
+	switch args[0] {
+	case "reconcile-roles":
+		return reconcileRoles(args[1:])
+	case "validate-model":
+		return validateModel(args[1:])
+	case "lint-policy":
+		return lintPolicy()
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n", args[0])
+		return 2
+	}
+}
+
+// reconcileRoles 输出JSON格式的对比结果；存在未修复的差异时返回1
+func reconcileRoles(args []string) int {
+	flags := flag.NewFlagSet("reconcile-roles", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "修复差异")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	report, err := service.NewAccountService().ReconcileAccountRoles(casbin.WithSystemPrincipal(context.Background()), *repair)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "对比账户角色失败: %v\n", err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(report)
+	for _, drift := range report.Drifts {
+		if !drift.Repaired {
+			return 1
+		}
+	}
+	return 0
+}
+
+// validateModel 校验模型文件，不替换当前执行器
+func validateModel(args []string) int {
+	modelPath := config.ViperConfig.Casbin.ModelPath
+	if len(args) > 0 {
+		modelPath = args[0]
+	}
+	if err := casbin.GetCasbinInstance().ValidateModel(modelPath); err != nil {
+		fmt.Fprintf(os.Stderr, "模型校验失败: %v\n", err)
+		return 1
+	}
+	fmt.Println("模型校验通过")
+	return 0
+}
+
+// lintPolicy 输出JSON格式的检查结果；发现问题时返回1
+func lintPolicy() int {
+	issues, err := casbin.GetCasbinInstance().LintPolicy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "检查策略失败: %v\n", err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(issues)
+	if len(issues) > 0 {
+		return 1
+	}
+	return 0
+}
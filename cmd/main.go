@@ -4,11 +4,15 @@ import (
 	"go_casbin/api"
 	"go_casbin/internal/config"
 	"go_casbin/internal/logger"
+	"go_casbin/internal/model"
+	"go_casbin/internal/service/authz"
 	"go_casbin/pkg/casbin"
 	"go_casbin/pkg/database"
 	"go_casbin/pkg/etcd"
 	"go_casbin/pkg/jwt"
 	"go_casbin/pkg/redis"
+	"os"
+	"time"
 )
 
 func init() {
@@ -28,6 +32,13 @@ func init() {
 		ParseTime: &config.ViperConfig.Database.ParseTime,
 		Loc: &config.ViperConfig.Database.Loc,
 	})
+	// sqlite和内存数据库启动时没有表结构，自动创建账户和角色表
+	if database.IsEmbedded(config.ViperConfig.Database.Driver) {
+		if err := database.AutoMigrate(&model.Account{}, &model.Role{}); err != nil {
+			logger.ErrorWithErr("创建数据表失败", err)
+			panic(err)
+		}
+	}
 	// 初始化redis连接
 	redis.InitRedis(redis.RedisOptions{
 		Addr: config.ViperConfig.Redis.Addr,
@@ -41,11 +52,54 @@ func init() {
 		Driver: config.ViperConfig.Casbin.Driver,
 		DataSource: config.ViperConfig.Casbin.DataSource,
 		ModelPath: config.ViperConfig.Casbin.ModelPath,
+		EnableDomain: config.ViperConfig.Casbin.EnableDomain,
+		EnableABAC: config.ViperConfig.Casbin.EnableABAC,
+		Domains: config.ViperConfig.Casbin.Domains,
+		LazyDomains: config.ViperConfig.Casbin.LazyDomains,
+		Strategy: casbin.ParseStrategy(config.ViperConfig.Casbin.Strategy),
 	})
 	if err != nil {
 		logger.ErrorWithErr("初始化CasbinService失败", err)
 		panic(err)
 	}
+	// 初始化鉴权结果缓存
+	if cacheConfig := config.ViperConfig.Casbin.Cache; cacheConfig.Enable {
+		cacheOptions := casbin.DecisionCacheOptions{
+			Capacity: cacheConfig.Capacity,
+			TTL: time.Duration(cacheConfig.TTL) * time.Second,
+		}
+		if cacheConfig.Redis {
+			redisClient := redis.GetRedisInstance()
+			cacheOptions.Redis = &redisClient
+		}
+		casbin.GetCasbinInstance().SetDecisionCache(casbin.NewDecisionCache(cacheOptions))
+	}
+	// 初始化策略快照
+	if config.ViperConfig.Casbin.Snapshot {
+		if err := casbin.GetCasbinInstance().EnableSnapshots(database.GetDB()); err != nil {
+			logger.ErrorWithErr("初始化策略快照失败", err)
+			panic(err)
+		}
+	}
+	// 初始化限时角色授权，后台定期使到期的授权生效或过期
+	if config.ViperConfig.Casbin.RoleGrant {
+		if err := casbin.GetCasbinInstance().EnableRoleGrants(database.GetDB()); err != nil {
+			logger.ErrorWithErr("初始化限时角色授权失败", err)
+			panic(err)
+		}
+		casbin.GetCasbinInstance().StartGrantScheduler(time.Duration(config.ViperConfig.Casbin.RoleGrantInterval) * time.Second)
+	}
+	// 按需加载的域空闲超时后移出内存
+	casbin.GetCasbinInstance().StartDomainEviction(time.Duration(config.ViperConfig.Casbin.DomainIdle) * time.Second)
+	// 监听模型文件，修改后校验通过才替换执行器
+	if config.ViperConfig.Casbin.ModelWatch {
+		if _, err := casbin.GetCasbinInstance().WatchModel(config.ViperConfig.Casbin.ModelPath); err != nil {
+			logger.ErrorWithErr("启用Casbin模型热加载失败", err)
+			panic(err)
+		}
+	}
+	// 注册资源关系加载器
+	authz.RegisterRelationLoaders(casbin.GetRelationChecker())
 	// 初始化etcd连接
 	etcd.InitEtcd(etcd.EtcdOptions{
 		Endpoints: config.ViperConfig.Etcd.Endpoints,
@@ -53,10 +107,19 @@ func init() {
 		Username: config.ViperConfig.Etcd.Username,
 		Password: config.ViperConfig.Etcd.Password,
 	})
+	// 初始化策略同步watcher，多副本部署时广播策略变更
+	if err := casbin.InitWatcher(config.ViperConfig.Casbin.Watcher, config.ViperConfig.Casbin.WatcherKey); err != nil {
+		logger.ErrorWithErr("初始化Casbin watcher失败", err)
+		panic(err)
+	}
 
 }
 
 func main() {
+	// 带参数时执行子命令，如 reconcile-roles -repair
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
 	r := api.InitRouter()
 	var port string = config.ViperConfig.Service.Port
 	if err := r.Run(port); err != nil {
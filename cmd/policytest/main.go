@@ -0,0 +1,53 @@
+// policytest 执行策略测试用例，不需要配置文件和数据库，可在CI中运行
+//
+//	go run ./cmd/policytest [-update] test/testdata/policies/*.yaml
+//
+// 全部通过时退出码为0，存在失败用例时为1，-update按实际结果改写用例文件中的allow
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go_casbin/internal/logger"
+	"go_casbin/pkg/casbin"
+	"os"
+)
+
+func main() {
+	update := flag.Bool("update", false, "按实际结果改写用例文件")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "用法: policytest [-update] <用例文件>...")
+		os.Exit(2)
+	}
+	logger.Init(nil)
+
+	failed := false
+	for _, file := range flag.Args() {
+		if *update {
+			changed, err := casbin.UpdatePolicySuite(file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("%s: 更新 %d 个用例\n", file, changed)
+			continue
+		}
+		result, err := casbin.RunPolicySuite(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			failed = true
+			continue
+		}
+		if !result.Passed() {
+			fmt.Print(result.Diff())
+			failed = true
+			continue
+		}
+		fmt.Printf("ok  %s: %d 个用例\n", file, len(result.Results))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
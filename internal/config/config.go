@@ -6,6 +6,7 @@ import (
 	"go_casbin/pkg/path"
 	"log"
 	"sync"
+	"sync/atomic"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
@@ -15,6 +16,9 @@ var ViperConfig Config
 var v *viper.Viper
 var once sync.Once
 
+// middleware 中间件配置快照，热更新时整体替换，请求路径只读取快照
+var middleware atomic.Pointer[MiddlewareConfig]
+
 // InitConfig 初始化配置，单例模式
 func InitConfig(configPath string) {
 	once.Do(func() {
@@ -45,7 +49,28 @@ func InitConfig(configPath string) {
 }
 
 func viperLoadConf() {
-	if err := v.Unmarshal(&ViperConfig); err != nil {
+	var conf Config
+	if err := v.Unmarshal(&conf); err != nil {
 		log.Fatalf("Unable to decode into config struct: %v", err)
 	}
+	SetMiddleware(conf.Middleware)
+	ViperConfig = conf
+}
+
+// Middleware 获取当前中间件配置快照，返回值只读，未初始化时为零值
+func Middleware() *MiddlewareConfig {
+	if cfg := middleware.Load(); cfg != nil {
+		return cfg
+	}
+	return &MiddlewareConfig{}
+}
+
+// SetMiddleware 发布新的中间件配置快照，复制map避免调用方后续修改影响快照
+func SetMiddleware(cfg MiddlewareConfig) {
+	groups := make(map[string]string, len(cfg.CasbinGroups))
+	for group, mode := range cfg.CasbinGroups {
+		groups[group] = mode
+	}
+	cfg.CasbinGroups = groups
+	middleware.Store(&cfg)
 }
@@ -25,6 +25,28 @@ type Casbin struct {
 	ModelPath  string `yaml:"modelPath" json:"modelPath" mapstructure:"modelPath"`
 	Driver     string `yaml:"driver" json:"driver" mapstructure:"driver"`
 	DataSource string `yaml:"dataSource" json:"dataSource" mapstructure:"dataSource"`
+	EnableDomain bool   `yaml:"enableDomain" json:"enableDomain" mapstructure:"enableDomain"` // 启用多租户模型
+	EnableABAC   bool   `yaml:"enableABAC" json:"enableABAC" mapstructure:"enableABAC"`       // 启用属性鉴权(r2/p2/m2)
+	DomainField  string `yaml:"domainField" json:"domainField" mapstructure:"domainField"`    // 域取值字段：tenant(默认)、system、app
+	Strategy     string `yaml:"strategy" json:"strategy" mapstructure:"strategy"`          // 多角色合并策略：any-allow(默认)、deny-overrides(模型的p定义需要包含eft)
+	Watcher      string `yaml:"watcher" json:"watcher" mapstructure:"watcher"`             // 多实例策略同步：etcd、redis，为空不启用
+	WatcherKey   string `yaml:"watcherKey" json:"watcherKey" mapstructure:"watcherKey"`    // 策略变更广播的etcd key或redis频道
+	Cache        CasbinCache `yaml:"cache" json:"cache" mapstructure:"cache"`              // 鉴权结果缓存
+	Snapshot     bool   `yaml:"snapshot" json:"snapshot" mapstructure:"snapshot"`          // 启用策略快照与回滚
+	RoleGrant    bool   `yaml:"roleGrant" json:"roleGrant" mapstructure:"roleGrant"`       // 启用限时角色授权
+	RoleGrantInterval int `yaml:"roleGrantInterval" json:"roleGrantInterval" mapstructure:"roleGrantInterval"` // 限时授权检查间隔（秒），默认60
+	ModelWatch   bool   `yaml:"modelWatch" json:"modelWatch" mapstructure:"modelWatch"`    // 监听模型文件，校验通过后热加载
+	Domains      []string `yaml:"domains" json:"domains" mapstructure:"domains"`             // 启用多租户时只加载这些域的策略，需要数据库存储
+	LazyDomains  bool   `yaml:"lazyDomains" json:"lazyDomains" mapstructure:"lazyDomains"` // 其他域在首次请求时加载
+	DomainIdle   int    `yaml:"domainIdle" json:"domainIdle" mapstructure:"domainIdle"`    // 按需加载的域空闲多久后移出内存（秒），0不移出
+}
+
+// CasbinCache 鉴权结果缓存配置
+type CasbinCache struct {
+	Enable   bool `yaml:"enable" json:"enable" mapstructure:"enable"`
+	Capacity int  `yaml:"capacity" json:"capacity" mapstructure:"capacity"` // 本地LRU容量
+	TTL      int  `yaml:"ttl" json:"ttl" mapstructure:"ttl"`                // 有效期（秒）
+	Redis    bool `yaml:"redis" json:"redis" mapstructure:"redis"`          // 启用redis二级缓存
 }
 
 type JWT struct {
@@ -97,6 +119,11 @@ type MiddlewareConfig struct {
 	
 	// 限流错误处理
 	UseRateLimitErrorHandler bool `yaml:"useRateLimitErrorHandler" json:"useRateLimitErrorHandler" mapstructure:"useRateLimitErrorHandler"`
+
+	// Casbin鉴权模式：enforce(默认)、shadow(只记录不拦截)、off，修改配置文件后即时生效
+	CasbinMode   string            `yaml:"casbinMode" json:"casbinMode" mapstructure:"casbinMode"`
+	CasbinGroups map[string]string `yaml:"casbinGroups" json:"casbinGroups" mapstructure:"casbinGroups"` // 按路由组覆盖鉴权模式，key为组名
+	CasbinObject string            `yaml:"casbinObject" json:"casbinObject" mapstructure:"casbinObject"` // 鉴权obj：route(路由模板，默认)或path(请求路径)
 }
 
 type Redis struct {
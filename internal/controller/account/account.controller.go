@@ -0,0 +1,79 @@
+package account
+
+import (
+	"go_casbin/internal/dto"
+	"go_casbin/internal/middleware/response"
+	"go_casbin/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountController interface {
+	GetAccount(c *gin.Context)
+	UpdateAccount(c *gin.Context)
+}
+
+type AccountControllerImpl struct {
+	accountService service.AccountService
+}
+
+func NewAccountController() AccountController {
+	return &AccountControllerImpl{
+		accountService: service.NewAccountService(),
+	}
+}
+
+// 获取账户，手机号、邮箱等字段按字段权限返回
+func (a *AccountControllerImpl) GetAccount(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.BadRequest(c, "账户ID不正确")
+		return
+	}
+	account, err := a.accountService.GetAccountByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if account == nil {
+		response.Error(c, http.StatusNotFound, "账户不存在")
+		return
+	}
+	response.Success(c, account)
+}
+
+// 更新账户，修改无写权限的字段会被字段权限中间件拒绝
+func (a *AccountControllerImpl) UpdateAccount(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.BadRequest(c, "账户ID不正确")
+		return
+	}
+	var req dto.AccountUpdateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	account, err := a.accountService.GetAccountByID(c.Request.Context(), uint(id))
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if account == nil {
+		response.Error(c, http.StatusNotFound, "账户不存在")
+		return
+	}
+	if req.Phone != nil {
+		account.Phone = req.Phone
+	}
+	if req.Email != nil {
+		account.Email = req.Email
+	}
+	if err := a.accountService.UpdateAccount(c.Request.Context(), account); err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, account)
+}
@@ -0,0 +1,104 @@
+package authz
+
+import (
+	"go_casbin/internal/dto"
+	casbinMiddleware "go_casbin/internal/middleware/casbin"
+	"go_casbin/internal/middleware/response"
+	authzService "go_casbin/internal/service/authz"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthzController interface {
+	Explain(c *gin.Context)
+	DryRun(c *gin.Context)
+	ShadowStats(c *gin.Context)
+	ListPermissions(c *gin.Context)
+	ValidatePermissions(c *gin.Context)
+	MyPermissions(c *gin.Context)
+}
+
+type AuthzControllerImpl struct {
+	authzService authzService.AuthzService
+}
+
+func NewAuthzController() AuthzController {
+	return &AuthzControllerImpl{
+		authzService: authzService.NewAuthzService(),
+	}
+}
+
+// 解释鉴权结果：命中的策略和角色链
+func (a *AuthzControllerImpl) Explain(c *gin.Context) {
+	var req dto.ExplainDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	account, _ := casbinMiddleware.GetAccount(c)
+	explanation, err := a.authzService.Explain(c.Request.Context(), account, req)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, explanation)
+}
+
+// 预演策略变更：报告哪些请求会由放行变为拒绝或相反
+func (a *AuthzControllerImpl) DryRun(c *gin.Context) {
+	var req dto.DryRunDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	result, err := a.authzService.DryRun(c.Request.Context(), req)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+// 影子模式统计：各路由组鉴权次数及将被拒绝的次数
+func (a *AuthzControllerImpl) ShadowStats(c *gin.Context) {
+	response.Success(c, a.authzService.ShadowStats(c.Request.Context()))
+}
+
+// 权限目录：由路由表生成，可按group过滤
+func (a *AuthzControllerImpl) ListPermissions(c *gin.Context) {
+	groups, permissions := a.authzService.ListPermissions(c.Request.Context(), c.Query("group"))
+	response.Success(c, gin.H{
+		"groups":      groups,
+		"permissions": permissions,
+	})
+}
+
+// 校验策略：列出指向不存在路由或方法的规则
+func (a *AuthzControllerImpl) ValidatePermissions(c *gin.Context) {
+	issues, err := a.authzService.ValidatePermissions(c.Request.Context())
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{
+		"valid":  len(issues) == 0,
+		"issues": issues,
+	})
+}
+
+// 当前账户可执行的权限：前端据此显示菜单和按钮，可按prefix过滤资源
+func (a *AuthzControllerImpl) MyPermissions(c *gin.Context) {
+	account, ok := casbinMiddleware.GetAccount(c)
+	if !ok {
+		response.Unauthorized(c, "缺少登录账户")
+		return
+	}
+	permissions, err := a.authzService.MyPermissions(c.Request.Context(), account, c.Query("prefix"))
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{
+		"permissions": permissions,
+	})
+}
@@ -0,0 +1,465 @@
+package policy
+
+import (
+	"fmt"
+	"go_casbin/internal/dto"
+	casbinMiddleware "go_casbin/internal/middleware/casbin"
+	"go_casbin/internal/middleware/response"
+	policyService "go_casbin/internal/service/policy"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PolicyController interface {
+	ListPolicies(c *gin.Context)
+	AddPolicy(c *gin.Context)
+	AddPolicies(c *gin.Context)
+	RemovePolicy(c *gin.Context)
+
+	ListRoles(c *gin.Context)
+	AssignRole(c *gin.Context)
+	UnassignRole(c *gin.Context)
+	ListRoleUsers(c *gin.Context)
+
+	CacheStats(c *gin.Context)
+	LintPolicies(c *gin.Context)
+
+	ExportPolicies(c *gin.Context)
+	ImportPolicies(c *gin.Context)
+
+	CreateSnapshot(c *gin.Context)
+	ListSnapshots(c *gin.Context)
+	DiffSnapshots(c *gin.Context)
+	RollbackSnapshot(c *gin.Context)
+
+	ListDataScopes(c *gin.Context)
+	SetDataScope(c *gin.Context)
+	RemoveDataScope(c *gin.Context)
+
+	ListFieldPolicies(c *gin.Context)
+	AddFieldPolicy(c *gin.Context)
+	RemoveFieldPolicy(c *gin.Context)
+
+	ListRoleGrants(c *gin.Context)
+	GrantRole(c *gin.Context)
+	RevokeRoleGrant(c *gin.Context)
+}
+
+type PolicyControllerImpl struct {
+	policyService policyService.PolicyService
+}
+
+func NewPolicyController() PolicyController {
+	return &PolicyControllerImpl{
+		policyService: policyService.NewPolicyService(),
+	}
+}
+
+// 分页查询策略
+func (p *PolicyControllerImpl) ListPolicies(c *gin.Context) {
+	var query dto.PolicyQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	query.Normalize()
+	policies, total, err := p.policyService.ListPolicies(c.Request.Context(), query)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.PaginatedResponse(c, policies, total, query.Page, query.PageSize)
+}
+
+// 添加策略
+func (p *PolicyControllerImpl) AddPolicy(c *gin.Context) {
+	var policy dto.PolicyDTO
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	ok, err := p.policyService.AddPolicy(c.Request.Context(), policy)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if !ok {
+		response.LogicError(c, "策略已存在")
+		return
+	}
+	response.Success(c, "添加成功")
+}
+
+// 批量添加策略
+func (p *PolicyControllerImpl) AddPolicies(c *gin.Context) {
+	var batch dto.BatchPolicyDTO
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	ok, err := p.policyService.AddPolicies(c.Request.Context(), batch.Policies)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if !ok {
+		response.LogicError(c, "存在重复的策略")
+		return
+	}
+	response.Success(c, "添加成功")
+}
+
+// 删除策略
+func (p *PolicyControllerImpl) RemovePolicy(c *gin.Context) {
+	var policy dto.PolicyDTO
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	ok, err := p.policyService.RemovePolicy(c.Request.Context(), policy)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if !ok {
+		response.LogicError(c, "策略不存在")
+		return
+	}
+	response.Success(c, "删除成功")
+}
+
+// 获取所有角色
+func (p *PolicyControllerImpl) ListRoles(c *gin.Context) {
+	roles, err := p.policyService.ListRoles(c.Request.Context(), c.Query("domain"))
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, roles)
+}
+
+// 给用户分配角色
+func (p *PolicyControllerImpl) AssignRole(c *gin.Context) {
+	var assign dto.RoleAssignDTO
+	if err := c.ShouldBindJSON(&assign); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	ok, err := p.policyService.AssignRole(c.Request.Context(), assign)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if !ok {
+		response.LogicError(c, "用户已拥有该角色")
+		return
+	}
+	response.Success(c, "分配成功")
+}
+
+// 移除用户的角色
+func (p *PolicyControllerImpl) UnassignRole(c *gin.Context) {
+	var assign dto.RoleAssignDTO
+	if err := c.ShouldBindJSON(&assign); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	ok, err := p.policyService.UnassignRole(c.Request.Context(), assign)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if !ok {
+		response.LogicError(c, "用户未拥有该角色")
+		return
+	}
+	response.Success(c, "移除成功")
+}
+
+// 获取角色下的用户
+func (p *PolicyControllerImpl) ListRoleUsers(c *gin.Context) {
+	users, err := p.policyService.ListRoleUsers(c.Request.Context(), c.Param("role"), c.Query("domain"))
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, users)
+}
+
+// 获取鉴权缓存命中统计
+func (p *PolicyControllerImpl) CacheStats(c *gin.Context) {
+	stats, err := p.policyService.CacheStats(c.Request.Context())
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, stats)
+}
+
+// 检查策略中的重复、孤立角色、空角色、继承环和被覆盖的规则
+func (p *PolicyControllerImpl) LintPolicies(c *gin.Context) {
+	issues, err := p.policyService.LintPolicies(c.Request.Context())
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{
+		"valid":  len(issues) == 0,
+		"issues": issues,
+	})
+}
+
+// 导出全部策略，以文件形式下载
+func (p *PolicyControllerImpl) ExportPolicies(c *gin.Context) {
+	var query dto.PolicyTransferQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	data, format, err := p.policyService.ExportPolicies(c.Request.Context(), query)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=policy.%s", format))
+	c.Data(http.StatusOK, format.ContentType(), data)
+}
+
+// 导入策略，支持上传文件(file字段)或直接提交请求体
+func (p *PolicyControllerImpl) ImportPolicies(c *gin.Context) {
+	var query dto.PolicyTransferQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	var reader io.Reader = c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		defer f.Close()
+		reader = f
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	result, err := p.policyService.ImportPolicies(c.Request.Context(), query, data)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+// operatorOf 当前操作人的用户名
+func operatorOf(c *gin.Context) string {
+	if account, ok := casbinMiddleware.GetAccount(c); ok {
+		return account.Username
+	}
+	return ""
+}
+
+// 保存当前策略为快照
+func (p *PolicyControllerImpl) CreateSnapshot(c *gin.Context) {
+	var req dto.SnapshotCreateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	snapshot, err := p.policyService.CreateSnapshot(c.Request.Context(), operatorOf(c), req)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, snapshot)
+}
+
+// 分页查询快照
+func (p *PolicyControllerImpl) ListSnapshots(c *gin.Context) {
+	var query dto.SnapshotQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	query.Normalize()
+	snapshots, total, err := p.policyService.ListSnapshots(c.Request.Context(), query)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.PaginatedResponse(c, snapshots, total, query.Page, query.PageSize)
+}
+
+// 对比两个快照
+func (p *PolicyControllerImpl) DiffSnapshots(c *gin.Context) {
+	var query dto.SnapshotDiffQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	diff, err := p.policyService.DiffSnapshots(c.Request.Context(), query)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, diff)
+}
+
+// 回滚到指定快照
+func (p *PolicyControllerImpl) RollbackSnapshot(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.BadRequest(c, "快照ID不正确")
+		return
+	}
+	backup, err := p.policyService.RollbackSnapshot(c.Request.Context(), operatorOf(c), uint(id))
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, backup)
+}
+
+// 获取全部数据范围策略
+func (p *PolicyControllerImpl) ListDataScopes(c *gin.Context) {
+	scopes, err := p.policyService.ListDataScopes(c.Request.Context())
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, scopes)
+}
+
+// 设置数据范围
+func (p *PolicyControllerImpl) SetDataScope(c *gin.Context) {
+	var req dto.DataScopeDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if _, err := p.policyService.SetDataScope(c.Request.Context(), req); err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, "设置成功")
+}
+
+// 删除数据范围
+func (p *PolicyControllerImpl) RemoveDataScope(c *gin.Context) {
+	var req dto.DataScopeDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	ok, err := p.policyService.RemoveDataScope(c.Request.Context(), req)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if !ok {
+		response.LogicError(c, "数据范围不存在")
+		return
+	}
+	response.Success(c, "删除成功")
+}
+
+// 获取全部字段权限策略
+func (p *PolicyControllerImpl) ListFieldPolicies(c *gin.Context) {
+	policies, err := p.policyService.ListFieldPolicies(c.Request.Context())
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, policies)
+}
+
+// 授予字段权限
+func (p *PolicyControllerImpl) AddFieldPolicy(c *gin.Context) {
+	var req dto.FieldPolicyDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	ok, err := p.policyService.AddFieldPolicy(c.Request.Context(), req)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if !ok {
+		response.LogicError(c, "字段权限已存在")
+		return
+	}
+	response.Success(c, "添加成功")
+}
+
+// 收回字段权限
+func (p *PolicyControllerImpl) RemoveFieldPolicy(c *gin.Context) {
+	var req dto.FieldPolicyDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	ok, err := p.policyService.RemoveFieldPolicy(c.Request.Context(), req)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	if !ok {
+		response.LogicError(c, "字段权限不存在")
+		return
+	}
+	response.Success(c, "删除成功")
+}
+
+// 分页查询限时角色授权
+func (p *PolicyControllerImpl) ListRoleGrants(c *gin.Context) {
+	var query dto.RoleGrantQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	query.Normalize()
+	grants, total, err := p.policyService.ListRoleGrants(c.Request.Context(), query)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.PaginatedResponse(c, grants, total, query.Page, query.PageSize)
+}
+
+// 创建限时角色授权
+func (p *PolicyControllerImpl) GrantRole(c *gin.Context) {
+	var req dto.RoleGrantDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	grant, err := p.policyService.GrantRole(c.Request.Context(), operatorOf(c), req)
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, grant)
+}
+
+// 撤销限时角色授权
+func (p *PolicyControllerImpl) RevokeRoleGrant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.BadRequest(c, "授权ID不正确")
+		return
+	}
+	grant, err := p.policyService.RevokeRoleGrant(c.Request.Context(), operatorOf(c), uint(id))
+	if err != nil {
+		response.LogicError(c, err.Error())
+		return
+	}
+	response.Success(c, grant)
+}
@@ -30,7 +30,7 @@ type WorkFlowControllerImpl struct {
 
 func NewWorkFlowController() WorkFlowController {
 	return &WorkFlowControllerImpl{
-		workFlowService: workService.NewWorkFlowServiceImpl(),
+		workFlowService: workService.GetWorkFlowService(),
 	}
 }
 
@@ -160,7 +160,7 @@ func (w *WorkFlowControllerImpl) GetWorkFlowInstanceList(c *gin.Context) {
 
 //审批工作流实例
 func (w *WorkFlowControllerImpl) ApproveWorkFlowInstance(c *gin.Context) {
-	err:=w.workFlowService.ApproveWorkFlowInstance(c.Request.Context(), "0", &workInstance.WorkflowStepInstance{
+	err:=w.workFlowService.ApproveWorkFlowInstance(c.Request.Context(), c.DefaultQuery("id", "0"), &workInstance.WorkflowStepInstance{
 		StepID: "1",
 		Approvals: map[string]bool{"1": false, "2": false},
 		Reason: map[string]string{"1": "不合适", "2": "不合适"},
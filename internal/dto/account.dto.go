@@ -0,0 +1,7 @@
+package dto
+
+// AccountUpdateDTO 本人更新账户，只修改请求中出现的字段；状态等管理字段不允许本人修改
+type AccountUpdateDTO struct {
+	Phone *string `json:"phone"` // 手机号
+	Email *string `json:"email"` // 邮箱
+}
@@ -0,0 +1,26 @@
+package dto
+
+// ExplainDTO 鉴权解释请求，(sub, obj, act) 与 (user_id, method, path) 二选一
+type ExplainDTO struct {
+	Sub    string   `json:"sub"`     // 主体
+	Domain string   `json:"domain"`  // 域：启用多租户时必填，按user_id解释当前账户时可省略(从token中取出)
+	Obj    string   `json:"obj"`     // 资源
+	Act    string   `json:"act"`     // 操作
+	UserID string   `json:"user_id"` // 用户ID
+	Roles  []string `json:"roles"`   // token中的角色，与用户ID一同参与鉴权；解释当前账户自己的请求时可省略
+	Method string   `json:"method"`  // HTTP方法
+	Path   string   `json:"path"`    // 路由模板(如/api/v1/accounts/:id)或请求路径
+}
+
+// PolicyLineDTO 一条策略或角色继承规则
+type PolicyLineDTO struct {
+	Ptype string   `json:"ptype" binding:"required"`      // p、g、g2...
+	Rule  []string `json:"rule" binding:"required,min=1"` // 规则字段
+}
+
+// DryRunDTO 策略变更预演请求
+type DryRunDTO struct {
+	Add      []PolicyLineDTO `json:"add" binding:"dive"`    // 新增的规则
+	Remove   []PolicyLineDTO `json:"remove" binding:"dive"` // 删除的规则
+	Requests [][]string      `json:"requests"`              // 待验证的请求，为空时使用最近的鉴权请求
+}
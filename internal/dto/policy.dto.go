@@ -0,0 +1,121 @@
+package dto
+
+import "time"
+
+// PolicyDTO 单条访问策略
+type PolicyDTO struct {
+	Sub    string `json:"sub" form:"sub" binding:"required"` // 主体：用户或角色
+	Domain string `json:"domain,omitempty" form:"domain"`    // 域：启用多租户时必填
+	Obj    string `json:"obj" form:"obj" binding:"required"` // 资源
+	Act    string `json:"act" form:"act" binding:"required"` // 操作
+}
+
+// PolicyQuery 策略列表查询条件
+type PolicyQuery struct {
+	Sub      string `form:"sub"`
+	Domain   string `form:"domain"`
+	Obj      string `form:"obj"`
+	Act      string `form:"act"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// Normalize 补全默认分页参数
+func (q *PolicyQuery) Normalize() {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = 10
+	}
+}
+
+// BatchPolicyDTO 批量添加策略
+type BatchPolicyDTO struct {
+	Policies []PolicyDTO `json:"policies" binding:"required,min=1,dive"`
+}
+
+// RoleAssignDTO 用户角色分配
+type RoleAssignDTO struct {
+	User   string `json:"user" binding:"required"` // 用户
+	Role   string `json:"role" binding:"required"` // 角色
+	Domain string `json:"domain,omitempty"`        // 域：启用多租户时必填
+}
+
+// PolicyTransferQuery 策略导入导出参数
+type PolicyTransferQuery struct {
+	Format string `form:"format"` // csv、json、yaml，默认csv
+	Mode   string `form:"mode"`   // 导入模式：merge(默认)、replace
+}
+
+// SnapshotCreateDTO 创建策略快照
+type SnapshotCreateDTO struct {
+	Comment string `json:"comment"` // 备注
+}
+
+// SnapshotQuery 策略快照列表查询条件
+type SnapshotQuery struct {
+	Page     int `form:"page"`
+	PageSize int `form:"page_size"`
+}
+
+// Normalize 补全默认分页参数
+func (q *SnapshotQuery) Normalize() {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = 10
+	}
+}
+
+// SnapshotDiffQuery 快照对比参数
+type SnapshotDiffQuery struct {
+	From uint `form:"from" binding:"required"` // 起始快照
+	To   uint `form:"to"`                      // 目标快照，为空时与当前策略对比
+}
+
+// DataScopeDTO 数据范围策略
+type DataScopeDTO struct {
+	Sub      string `json:"sub" binding:"required"`      // 主体：用户或角色
+	Domain   string `json:"domain,omitempty"`            // 域：启用多租户时必填
+	Resource string `json:"resource" binding:"required"` // 资源类型，*表示全部
+	Scope    string `json:"scope"`                       // all、tenant、department、self，删除时不需要
+}
+
+// FieldPolicyDTO 字段级权限策略
+type FieldPolicyDTO struct {
+	Sub    string `json:"sub" binding:"required"`                       // 主体：用户或角色
+	Domain string `json:"domain,omitempty"`                             // 域：启用多租户时必填
+	Object string `json:"object" binding:"required"`                    // 对象，如account
+	Field  string `json:"field" binding:"required"`                     // 字段，JSON字段名，*表示全部
+	Action string `json:"action" binding:"required,oneof=read write *"` // read、write或*
+}
+
+// RoleGrantDTO 限时角色授权
+type RoleGrantDTO struct {
+	User    string     `json:"user" binding:"required"` // 用户
+	Role    string     `json:"role" binding:"required"` // 角色
+	Domain  string     `json:"domain,omitempty"`        // 域：启用多租户时必填
+	StartAt *time.Time `json:"start_at"`                // 生效时间，为空时立即生效
+	EndAt   *time.Time `json:"end_at"`                  // 过期时间，为空表示不过期
+	Reason  string     `json:"reason"`                  // 授权原因
+}
+
+// RoleGrantQuery 限时角色授权列表查询条件
+type RoleGrantQuery struct {
+	User     string `form:"user"`
+	Status   string `form:"status"` // pending、active、expired、revoked
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// Normalize 补全默认分页参数
+func (q *RoleGrantQuery) Normalize() {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = 10
+	}
+}
@@ -0,0 +1,89 @@
+package casbin
+
+import (
+	"go_casbin/internal/logger"
+	"go_casbin/internal/middleware/response"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/jwt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResourceFunc 从请求中解析被访问资源的属性(如查询资源所有者)
+type ResourceFunc func(c *gin.Context) (casbinService.Resource, error)
+
+// CasbinABAC 属性鉴权中间件：主体取自token，环境为请求时间和客户端IP，资源由resolve解析
+// resolve为空时资源只包含请求路径和路由参数id；鉴权模式与CasbinAuthGroup相同
+func CasbinABAC(group string, resolve ResourceFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mode := ModeOf(group)
+		if mode == ModeOff {
+			c.Next()
+			return
+		}
+		if mode == ModeShadow {
+			shadowCounterOf(group).evaluated.Add(1)
+		}
+
+		enforcer := casbinService.GetCasbinInstance()
+		account, exists := GetAccount(c)
+		if !exists || account.ID == "" {
+			reject(c, mode, group, "无权限", "缺少登录账户")
+			return
+		}
+		attachPrincipal(c, account)
+		resource := DefaultResource(c)
+		if resolve != nil {
+			var err error
+			if resource, err = resolve(c); err != nil {
+				reject(c, mode, group, "无权限", "解析资源失败: "+err.Error(), logger.String("account_id", account.ID))
+				return
+			}
+		}
+		sub := SubjectOf(account)
+		ok, policy, err := enforcer.EnforceABACEx(sub, resource, c.Request.Method, casbinService.NewEnvironment(time.Now(), c.ClientIP()))
+		if err != nil {
+			if mode == ModeShadow {
+				logger.ErrorWithErr("Casbin影子模式属性鉴权失败", err, logger.String("trace_id", response.GetTraceID(c)))
+				c.Next()
+				return
+			}
+			response.InternalServerError(c, err.Error())
+			c.Abort()
+			return
+		}
+		if !ok {
+			reject(c, mode, group, "无权限", "没有满足条件的属性策略", logger.String("account_id", account.ID))
+			return
+		}
+		c.Set(ReasonKey, "命中属性策略 ["+strings.Join(policy, ", ")+"]")
+		c.Next()
+	}
+}
+
+// DefaultResource 默认资源属性：路由模板和路由参数id
+func DefaultResource(c *gin.Context) casbinService.Resource {
+	return casbinService.Resource{
+		Path: RouteObject(c),
+		ID:   c.Param("id"),
+	}
+}
+
+// SubjectOf 由token中的账户构造ABAC主体属性
+func SubjectOf(account *jwt.Account) casbinService.Subject {
+	sub := casbinService.Subject{
+		ID:         account.ID,
+		Username:   account.Username,
+		Roles:      account.Role,
+		Platform:   account.Platform,
+		Status:     int(account.Status),
+		IsVerified: account.IsVerified,
+		IsLocked:   account.IsLocked,
+	}
+	if casbinService.GetCasbinInstance().IsDomainEnabled() {
+		sub.Domain = DomainOf(account)
+	}
+	return sub
+}
@@ -1,34 +1,153 @@
 package casbin
 
 import (
+	"go_casbin/internal/config"
+	"go_casbin/internal/logger"
 	"go_casbin/internal/middleware/response"
 	casbinService "go_casbin/pkg/casbin"
 	"go_casbin/pkg/jwt"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
+const (
+	// DecisionKey 上下文中保存鉴权决策的key
+	DecisionKey = "casbin_decision"
+	// ReasonKey 上下文中保存决策原因的key
+	ReasonKey = "casbin_reason"
+	// ShadowDeniedKey 影子模式下请求本应被拒绝时在上下文中置为true
+	ShadowDeniedKey = "casbin_shadow_denied"
+)
+
+// CasbinAuth 使用默认路由组的鉴权模式
 func CasbinAuth() gin.HandlerFunc {
+	return CasbinAuthGroup(DefaultGroup)
+}
+
+// CasbinAuthGroup 按路由组配置的模式鉴权：enforce拦截、shadow只记录、off跳过
+func CasbinAuthGroup(group string) gin.HandlerFunc {
+	return CasbinAuthWithOptions(AuthOptions{Group: group})
+}
+
+// CasbinAuthWithOptions 使用自定义obj、act提取方式鉴权，默认以路由模板为obj、HTTP方法为act
+func CasbinAuthWithOptions(opts AuthOptions) gin.HandlerFunc {
+	opts = opts.withDefaults()
+	group := opts.Group
 	return func(c *gin.Context) {
-		casbinService := casbinService.GetCasbinInstance()
-		accountVal, _ := c.Get("account")
-		account, _ := accountVal.(jwt.Account)
-		if account.Role == nil {
-			response.Forbidden(c, "无权限")
-			c.Abort()
+		mode := ModeOf(group)
+		if mode == ModeOff {
+			c.Next()
+			return
+		}
+		if mode == ModeShadow {
+			shadowCounterOf(group).evaluated.Add(1)
+		}
+
+		enforcer := casbinService.GetCasbinInstance()
+		account, exists := GetAccount(c)
+		if !exists || account.ID == "" && len(account.Role) == 0 {
+			reject(c, mode, group, "无权限", "缺少登录账户")
 			return
 		}
-		ok,err:=casbinService.Enforce(account.Role[0], c.Request.Method, c.Request.URL.Path)
+		attachPrincipal(c, account)
+		obj, act := opts.Object(c), opts.Action(c)
+		var rvals []interface{}
+		if enforcer.IsDomainEnabled() {
+			// 多租户模式：从token中取域，不同租户的策略相互隔离
+			domain := DomainOf(account)
+			if domain == "" {
+				reject(c, mode, group, "缺少租户信息", "缺少域信息", logger.String("account_id", account.ID))
+				return
+			}
+			rvals = RequestValues(domain, obj, act)
+		} else {
+			rvals = RequestValues("", obj, act)
+		}
+
+		// 先以用户本身鉴权(经由g策略继承角色)，再依次使用token中的全部角色
+		decision, err := enforcer.EnforceSubjects(enforcer.Strategy(), Subjects(account), rvals...)
 		if err != nil {
+			if mode == ModeShadow {
+				logger.ErrorWithErr("Casbin影子模式鉴权失败", err, logger.String("trace_id", response.GetTraceID(c)))
+				c.Next()
+				return
+			}
 			response.InternalServerError(c, err.Error())
 			c.Abort()
 			return
 		}
-		if !ok {
-			response.Forbidden(c, "无权限")
-			c.Abort()
+		c.Set(DecisionKey, decision)
+		c.Set(ReasonKey, decision.Reason)
+		if !decision.Allowed {
+			reject(c, mode, group, "无权限", decision.Reason, logger.String("account_id", account.ID), logger.String("obj", obj), logger.String("act", act))
 			return
 		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// reject 拒绝请求并记录日志；影子模式下只记录和计数，请求继续执行
+func reject(c *gin.Context, mode Mode, group, message, reason string, fields ...zap.Field) {
+	fields = append(fields,
+		logger.String("group", group),
+		logger.String("method", c.Request.Method),
+		logger.String("path", c.Request.URL.Path),
+		logger.String("reason", reason),
+		logger.String("trace_id", response.GetTraceID(c)),
+	)
+	if mode == ModeShadow {
+		shadowCounterOf(group).denied.Add(1)
+		c.Set(ShadowDeniedKey, true)
+		logger.Warn("Casbin影子模式 - 请求将被拒绝", fields...)
+		c.Next()
+		return
+	}
+	logger.Warn("Casbin鉴权拒绝", fields...)
+	response.Forbidden(c, message)
+	c.Abort()
+}
+
+// RequestValues 构造主体之后的请求参数，与模型的请求定义一致：(dom, obj, act)，domain为空时为(obj, act)
+func RequestValues(domain, obj, act string) []interface{} {
+	if domain != "" {
+		return []interface{}{domain, obj, act}
+	}
+	return []interface{}{obj, act}
+}
+
+// Subjects 参与鉴权的主体：用户ID在前，随后是token中的全部角色
+func Subjects(account *jwt.Account) []string {
+	subjects := make([]string, 0, len(account.Role)+1)
+	if account.ID != "" {
+		subjects = append(subjects, account.ID)
+	}
+	return append(subjects, account.Role...)
+}
+
+// GetAccount 从上下文获取当前登录账户，兼容指针和值两种存储方式
+func GetAccount(c *gin.Context) (*jwt.Account, bool) {
+	accountVal, exists := c.Get("account")
+	if !exists {
+		return nil, false
+	}
+	switch account := accountVal.(type) {
+	case *jwt.Account:
+		return account, account != nil
+	case jwt.Account:
+		return &account, true
+	}
+	return nil, false
+}
+
+// DomainOf 根据配置的domainField从账户中取出域
+func DomainOf(account *jwt.Account) string {
+	switch config.ViperConfig.Casbin.DomainField {
+	case "system":
+		return account.SystemId
+	case "app":
+		return account.AppId
+	default:
+		return account.TenantId
+	}
+}
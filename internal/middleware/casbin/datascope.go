@@ -0,0 +1,37 @@
+package casbin
+
+import (
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrincipalOf 由token中的账户构造数据范围主体
+func PrincipalOf(account *jwt.Account) *casbinService.Principal {
+	p := &casbinService.Principal{
+		UserID:       account.ID,
+		TenantID:     account.TenantId,
+		DepartmentID: account.DepartmentId,
+		Subjects:     Subjects(account),
+	}
+	if casbinService.GetCasbinInstance().IsDomainEnabled() {
+		p.Domain = DomainOf(account)
+	}
+	return p
+}
+
+// attachPrincipal 将当前账户放入请求上下文，仓储层据此自动追加数据范围条件
+func attachPrincipal(c *gin.Context, account *jwt.Account) {
+	c.Request = c.Request.WithContext(casbinService.WithPrincipal(c.Request.Context(), PrincipalOf(account)))
+}
+
+// DataScope 只注入数据范围主体，不做接口鉴权，用于仅需登录即可访问的列表接口
+func DataScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if account, exists := GetAccount(c); exists {
+			attachPrincipal(c, account)
+		}
+		c.Next()
+	}
+}
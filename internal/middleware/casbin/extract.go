@@ -0,0 +1,63 @@
+package casbin
+
+import (
+	"go_casbin/internal/config"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Extractor 从请求中取出鉴权使用的obj或act
+type Extractor func(c *gin.Context) string
+
+// 对象提取方式，对应配置middleware.casbinObject
+const (
+	ObjectRoute = "route" // 路由模板，如/api/v1/accounts/:id(默认)
+	ObjectPath  = "path"  // 实际请求路径，如/api/v1/accounts/123
+)
+
+// RouteObject 使用路由模板作为obj，策略按路由编写，与权限目录一致；未匹配到路由时退回请求路径
+func RouteObject(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return c.Request.URL.Path
+}
+
+// PathObject 使用实际请求路径作为obj，适用于按具体资源路径编写的策略
+func PathObject(c *gin.Context) string {
+	return c.Request.URL.Path
+}
+
+// MethodAction 使用HTTP方法作为act
+func MethodAction(c *gin.Context) string {
+	return c.Request.Method
+}
+
+// DefaultObject 按配置选择对象提取方式，每次请求时读取配置快照以支持热更新
+func DefaultObject(c *gin.Context) string {
+	if strings.ToLower(config.Middleware().CasbinObject) == ObjectPath {
+		return PathObject(c)
+	}
+	return RouteObject(c)
+}
+
+// AuthOptions CasbinAuth的可选配置，字段为空时使用默认值
+type AuthOptions struct {
+	Group  string    // 路由组，决定鉴权模式，默认DefaultGroup
+	Object Extractor // obj提取，默认DefaultObject
+	Action Extractor // act提取，默认MethodAction
+}
+
+func (o AuthOptions) withDefaults() AuthOptions {
+	if o.Group == "" {
+		o.Group = DefaultGroup
+	}
+	if o.Object == nil {
+		o.Object = DefaultObject
+	}
+	if o.Action == nil {
+		o.Action = MethodAction
+	}
+	return o
+}
@@ -0,0 +1,204 @@
+package casbin
+
+import (
+	"bytes"
+	"encoding/json"
+	"go_casbin/internal/logger"
+	"go_casbin/internal/middleware/response"
+	casbinService "go_casbin/pkg/casbin"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldRule 受保护的字段：JSON字段名，以及无读权限时的脱敏函数，Mask为空时直接去除字段
+type FieldRule struct {
+	Field string
+	Mask  func(value string) string
+}
+
+var (
+	fieldMu         sync.RWMutex
+	protectedFields = make(map[string][]FieldRule)
+)
+
+// RegisterFields 注册对象的受保护字段，未注册的字段不做字段级校验
+func RegisterFields(object string, rules ...FieldRule) {
+	fieldMu.Lock()
+	defer fieldMu.Unlock()
+	protectedFields[object] = rules
+}
+
+func fieldsOf(object string) []FieldRule {
+	fieldMu.RLock()
+	defer fieldMu.RUnlock()
+	return protectedFields[object]
+}
+
+// MaskMiddle 保留前head个和后tail个字符，中间替换为*
+func MaskMiddle(head, tail int) func(string) string {
+	return func(value string) string {
+		runes := []rune(value)
+		if len(runes) <= head+tail {
+			return strings.Repeat("*", len(runes))
+		}
+		return string(runes[:head]) + strings.Repeat("*", len(runes)-head-tail) + string(runes[len(runes)-tail:])
+	}
+}
+
+// MaskEmail 邮箱只保留用户名首字符和域名
+func MaskEmail(value string) string {
+	at := strings.LastIndex(value, "@")
+	if at <= 0 {
+		return MaskMiddle(0, 0)(value)
+	}
+	_, size := utf8.DecodeRuneInString(value)
+	return value[:size] + "***" + value[at:]
+}
+
+// FieldPermission 字段级权限中间件，放在CasbinAuth之后使用
+// 写请求的JSON中包含无write权限的受保护字段时拒绝；响应中无read权限的字段经response.Success去除或脱敏
+func FieldPermission(object string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules := fieldsOf(object)
+		if len(rules) == 0 {
+			c.Next()
+			return
+		}
+		enforcer := casbinService.GetCasbinInstance()
+		var subjects []string
+		var domain string
+		if account, exists := GetAccount(c); exists {
+			subjects = Subjects(account)
+			if enforcer.IsDomainEnabled() {
+				domain = DomainOf(account)
+			}
+		}
+
+		if isWriteMethod(c.Request.Method) && c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				response.BadRequest(c, "读取请求体失败")
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+			written := bodyFields(body)
+			var denied []string
+			for _, rule := range rules {
+				if !written[rule.Field] {
+					continue
+				}
+				ok, err := enforcer.EnforceField(subjects, domain, object, rule.Field, casbinService.FieldWrite)
+				if err != nil {
+					response.InternalServerError(c, err.Error())
+					c.Abort()
+					return
+				}
+				if !ok {
+					denied = append(denied, rule.Field)
+				}
+			}
+			if len(denied) > 0 {
+				logger.Warn("字段权限拒绝写入",
+					logger.String("object", object),
+					logger.Field("fields", denied),
+					logger.Field("subjects", subjects),
+					logger.String("trace_id", response.GetTraceID(c)),
+				)
+				response.Forbidden(c, "无权修改字段: "+strings.Join(denied, ", "))
+				c.Abort()
+				return
+			}
+		}
+
+		// 校验失败的字段同样视为不可读
+		hidden := make(map[string]FieldRule)
+		for _, rule := range rules {
+			ok, err := enforcer.EnforceField(subjects, domain, object, rule.Field, casbinService.FieldRead)
+			if err != nil || !ok {
+				hidden[rule.Field] = rule
+			}
+		}
+		if len(hidden) > 0 {
+			c.Set(response.FieldFilterKey, response.FieldFilter(func(data interface{}) interface{} {
+				return redact(data, hidden)
+			}))
+		}
+		c.Next()
+	}
+}
+
+func isWriteMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// bodyFields 请求体JSON中出现的全部字段名(含嵌套对象)，非JSON请求体返回空
+func bodyFields(body []byte) map[string]bool {
+	fields := make(map[string]bool)
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return fields
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for key, item := range val {
+				fields[key] = true
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		}
+	}
+	walk(payload)
+	return fields
+}
+
+// redact 将数据转为JSON结构后去除或脱敏字段，嵌套对象和列表中的同名字段一并处理
+func redact(data interface{}, hidden map[string]FieldRule) interface{} {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		logger.ErrorWithErr("字段权限过滤失败", err)
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		logger.ErrorWithErr("字段权限过滤失败", err)
+		return nil
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for key, item := range val {
+				rule, ok := hidden[key]
+				if !ok {
+					walk(item)
+					continue
+				}
+				if s, isString := item.(string); isString && rule.Mask != nil {
+					val[key] = rule.Mask(s)
+				} else if item != nil {
+					delete(val, key)
+				}
+			}
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		}
+	}
+	walk(payload)
+	return payload
+}
@@ -0,0 +1,76 @@
+package casbin
+
+import (
+	"go_casbin/internal/config"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Mode CasbinAuth鉴权模式
+type Mode string
+
+const (
+	ModeEnforce Mode = "enforce" // 拒绝时拦截请求
+	ModeShadow  Mode = "shadow"  // 只记录将被拒绝的请求，不拦截
+	ModeOff     Mode = "off"     // 不鉴权
+)
+
+// DefaultGroup 未指定路由组时使用的组名
+const DefaultGroup = "default"
+
+// ModeOf 读取路由组的鉴权模式，每次请求时读取配置快照以支持热更新
+// 优先使用casbinGroups中该组的配置，其次casbinMode，都未配置时为enforce
+func ModeOf(group string) Mode {
+	cfg := config.Middleware()
+	if mode, ok := cfg.CasbinGroups[group]; ok && mode != "" {
+		return parseMode(mode)
+	}
+	return parseMode(cfg.CasbinMode)
+}
+
+func parseMode(s string) Mode {
+	switch Mode(strings.ToLower(s)) {
+	case ModeShadow:
+		return ModeShadow
+	case ModeOff:
+		return ModeOff
+	default:
+		return ModeEnforce
+	}
+}
+
+// ShadowStats 影子模式下各路由组的鉴权统计
+type ShadowStats struct {
+	Evaluated uint64 `json:"evaluated"` // 鉴权次数
+	Denied    uint64 `json:"denied"`    // 将被拒绝的次数
+}
+
+type shadowCounter struct {
+	evaluated atomic.Uint64
+	denied    atomic.Uint64
+}
+
+var shadowCounters sync.Map // group -> *shadowCounter
+
+func shadowCounterOf(group string) *shadowCounter {
+	if counter, ok := shadowCounters.Load(group); ok {
+		return counter.(*shadowCounter)
+	}
+	counter, _ := shadowCounters.LoadOrStore(group, &shadowCounter{})
+	return counter.(*shadowCounter)
+}
+
+// GetShadowStats 获取影子模式统计，key为路由组名
+func GetShadowStats() map[string]ShadowStats {
+	stats := make(map[string]ShadowStats)
+	shadowCounters.Range(func(key, value interface{}) bool {
+		counter := value.(*shadowCounter)
+		stats[key.(string)] = ShadowStats{
+			Evaluated: counter.evaluated.Load(),
+			Denied:    counter.denied.Load(),
+		}
+		return true
+	})
+	return stats
+}
@@ -0,0 +1,62 @@
+package casbin
+
+import (
+	"errors"
+	"go_casbin/internal/logger"
+	"go_casbin/internal/middleware/response"
+	casbinService "go_casbin/pkg/casbin"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RelationKey 上下文中保存命中的资源关系的key
+const RelationKey = "casbin_relation"
+
+// RequireRelation 资源关系校验中间件，放在CasbinAuth之后使用
+// 资源ID依次从路由参数、查询参数中的idParam读取，当前用户(或其角色)须与资源存在relations中的任一关系
+func RequireRelation(resourceType, idParam string, relations ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account, exists := GetAccount(c)
+		if !exists {
+			response.Forbidden(c, "无权限")
+			c.Abort()
+			return
+		}
+		id := c.Param(idParam)
+		if id == "" {
+			id = c.Query(idParam)
+		}
+		if id == "" {
+			response.BadRequest(c, "缺少资源ID")
+			c.Abort()
+			return
+		}
+
+		ok, relation, err := casbinService.GetRelationChecker().Check(c.Request.Context(), Subjects(account), resourceType, id, relations...)
+		if err != nil {
+			if errors.Is(err, casbinService.ErrResourceNotFound) {
+				response.Error(c, http.StatusNotFound, "资源不存在")
+				c.Abort()
+				return
+			}
+			response.InternalServerError(c, err.Error())
+			c.Abort()
+			return
+		}
+		if !ok {
+			logger.Warn("资源关系校验拒绝",
+				logger.String("account_id", account.ID),
+				logger.String("resource", resourceType),
+				logger.String("id", id),
+				logger.Field("relations", relations),
+				logger.String("trace_id", response.GetTraceID(c)),
+			)
+			response.Forbidden(c, "无权操作该资源")
+			c.Abort()
+			return
+		}
+		c.Set(RelationKey, relation)
+		c.Next()
+	}
+}
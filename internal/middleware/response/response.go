@@ -20,6 +20,22 @@ type Response struct {
 	TraceID   string      `json:"trace_id"`  // 追踪ID
 }
 
+// FieldFilterKey 上下文中保存响应数据过滤函数的key，由字段权限中间件设置
+const FieldFilterKey = "response_field_filter"
+
+// FieldFilter 在写出响应前处理数据，如去除或脱敏调用方无权读取的字段
+type FieldFilter func(data interface{}) interface{}
+
+// filterData 存在过滤函数时处理响应数据
+func filterData(c *gin.Context, data interface{}) interface{} {
+	if value, exists := c.Get(FieldFilterKey); exists {
+		if filter, ok := value.(FieldFilter); ok && data != nil {
+			return filter(data)
+		}
+	}
+	return data
+}
+
 // ResponseMiddleware 统一响应格式中间件
 func ResponseMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -47,7 +63,7 @@ func ResponseMiddleware() gin.HandlerFunc {
 			response := Response{
 				Code:      http.StatusOK,
 				Message:   "success",
-				Data:      data,
+				Data:      filterData(c, data),
 				Timestamp: time.Now().Unix(),
 				Path:      c.Request.URL.Path,
 				Method:    c.Request.Method,
@@ -90,12 +106,12 @@ func ResponseLogger() gin.HandlerFunc {
 		)
 	}
 } 
-// Success 成功响应
+// Success 成功响应，数据经过字段权限过滤
 func Success(c *gin.Context, data interface{}) {
 	response := Response{
 		Code:      http.StatusOK,
 		Message:   "success",
-		Data:      data,
+		Data:      filterData(c, data),
 		Timestamp: time.Now().Unix(),
 		Path:      c.Request.URL.Path,
 		Method:    c.Request.Method,
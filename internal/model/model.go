@@ -4,13 +4,15 @@ import "gorm.io/gorm"
 
 type Account struct {
 	gorm.Model
-	Name     string  `gorm:"size:50;uniqueIndex;not null" json:"name"` // 用户名
-	Phone    *string `gorm:"size:20" json:"phone"`                     // 手机号 可为空
-	Email    *string `gorm:"size:100;uniqueIndex" json:"email"`        // 邮箱 可为空
-	Password string  `gorm:"size:255;not null" json:"-"`               // 密码
-	Status   int     `gorm:"default:1" json:"status"`                  // 状态：1-正常，0-禁用
-	Type     int     `gorm:"default:1" json:"type"`                    // 类型：1-用户，2-管理员
-	Roles    []Role  `gorm:"many2many:account_roles;" json:"roles"`    // 角色
+	Name         string  `gorm:"size:50;uniqueIndex;not null" json:"name"` // 用户名
+	Phone        *string `gorm:"size:20" json:"phone"`                     // 手机号 可为空
+	Email        *string `gorm:"size:100;uniqueIndex" json:"email"`        // 邮箱 可为空
+	Password     string  `gorm:"size:255;not null" json:"-"`               // 密码
+	Status       int     `gorm:"default:1" json:"status"`                  // 状态：1-正常，0-禁用
+	Type         int     `gorm:"default:1" json:"type"`                    // 类型：1-用户，2-管理员
+	TenantID     string  `gorm:"size:64;index" json:"tenant_id"`           // 租户ID，用于数据范围过滤
+	DepartmentID string  `gorm:"size:64;index" json:"department_id"`       // 部门ID，用于数据范围过滤
+	Roles        []Role  `gorm:"many2many:account_roles;" json:"roles"`    // 角色
 }
 
 type Role struct {
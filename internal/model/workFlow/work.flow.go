@@ -33,6 +33,10 @@ type WorkflowInstance struct {
 	Steps   []WorkflowStepInstance `json:"steps"`   // 存储 WorkflowStepInstance 列表
 	Done    bool           `json:"done"`    // 是否完成
 	Status  int            `json:"status"`  // 状态 0:Pending, 1:Approved, 2:Rejected
+
+	TenantID     string `gorm:"size:64;index" json:"tenant_id"`     // 租户ID，用于数据范围过滤
+	DepartmentID string `gorm:"size:64;index" json:"department_id"` // 部门ID，用于数据范围过滤
+	CreatedBy    string `gorm:"size:64;index" json:"created_by"`    // 发起人ID
 }
 
 // 步骤实例结构体（不直接建表，序列化进 JSON）
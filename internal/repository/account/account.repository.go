@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"go_casbin/internal/model"
+	"go_casbin/pkg/casbin"
 	"go_casbin/pkg/database"
 
 	"gorm.io/gorm"
@@ -33,11 +34,30 @@ type AccountRepositoryImpl struct {
 	db *gorm.DB
 }
 
+// DataScopeResource 账户在数据范围策略(p3)中的资源类型
+const DataScopeResource = "account"
+
+// ErrAccountNotFound 账户不存在或不在当前主体的数据范围内
+var ErrAccountNotFound = errors.New("账户不存在")
+
+func init() {
+	casbin.RegisterDataScopeColumns(DataScopeResource, casbin.DataScopeColumns{
+		Tenant:     "tenant_id",
+		Department: "department_id",
+		Owner:      "id",
+	})
+}
+
 // NewAccountRepository 创建账户仓储
 func NewAccountRepository() AccountRepository {
 	return &AccountRepositoryImpl{db: database.GetDB()}
 }
 
+// scoped 按请求上下文中的主体追加数据范围条件，按用户名、邮箱、手机号的唯一性查询不受限制
+func (r *AccountRepositoryImpl) scoped(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Scopes(casbin.DataScopeQuery(ctx, DataScopeResource))
+}
+
 // Create 创建账户
 func (r *AccountRepositoryImpl) Create(ctx context.Context, account *model.Account) error {
 	return r.db.WithContext(ctx).Create(account).Error
@@ -46,7 +66,7 @@ func (r *AccountRepositoryImpl) Create(ctx context.Context, account *model.Accou
 // FindByID 根据ID查找账户
 func (r *AccountRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Account, error) {
 	var account model.Account
-	err := r.db.WithContext(ctx).Preload("Roles").First(&account, id).Error
+	err := r.scoped(ctx).Preload("Roles").First(&account, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -98,59 +118,99 @@ func (r *AccountRepositoryImpl) FindByPhone(ctx context.Context, phone string) (
 //根据角色ID查找账户
 func (r *AccountRepositoryImpl) FindByRoleID(ctx context.Context, roleID uint) ([]*model.Account, error) {
 	var accounts []*model.Account
-	err := r.db.WithContext(ctx).Preload("Roles").Where("roles.id = ?", roleID).Find(&accounts).Error
+	err := r.scoped(ctx).Preload("Roles").Where("roles.id = ?", roleID).Find(&accounts).Error
 	return accounts, err
 }
 
-// Update 更新账户
+// Update 更新账户，不在数据范围内时返回ErrAccountNotFound
+// 不使用Save：Save在没有更新到记录时会改为插入，绕过数据范围条件
 func (r *AccountRepositoryImpl) Update(ctx context.Context, account *model.Account) error {
-	return r.db.WithContext(ctx).Save(account).Error
+	result := r.scoped(ctx).Model(account).Select("*").Updates(account)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// checkScope 确认账户在数据范围内，关联表的写入无法直接追加数据范围条件
+func (r *AccountRepositoryImpl) checkScope(ctx context.Context, id uint) error {
+	var count int64
+	if err := r.scoped(ctx).Model(&model.Account{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
 }
 
 //替换账户的角色
 func (r *AccountRepositoryImpl) ReplaceAccountRoles(ctx context.Context, account *model.Account, roles []model.Role) error {
+	if err := r.checkScope(ctx, account.ID); err != nil {
+		return err
+	}
 	return r.db.WithContext(ctx).Model(account).Association("Roles").Replace(roles)
 }
 
 //追加账户的角色
 func (r *AccountRepositoryImpl) AppendAccountRoles(ctx context.Context, account *model.Account, roles []model.Role) error {
+	if err := r.checkScope(ctx, account.ID); err != nil {
+		return err
+	}
 	return r.db.WithContext(ctx).Model(account).Association("Roles").Append(roles)
 }
 
-// Delete 删除账户
+// Delete 删除账户，不在数据范围内时返回ErrAccountNotFound
 func (r *AccountRepositoryImpl) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&model.Account{}, id).Error
+	result := r.scoped(ctx).Delete(&model.Account{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
 }
 
 // FindByCondition 根据条件查找账户
 func (r *AccountRepositoryImpl) FindByCondition(ctx context.Context, condition string, args ...interface{}) ([]*model.Account, error) {
 	var accounts []*model.Account
-	err := r.db.WithContext(ctx).Preload("Roles").Where(condition, args...).Find(&accounts).Error
+	err := r.scoped(ctx).Preload("Roles").Where(condition, args...).Find(&accounts).Error
 	return accounts, err
 }
 
 // Count 统计账户数量
 func (r *AccountRepositoryImpl) Count(ctx context.Context, condition string, args ...interface{}) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&model.Account{}).Preload("Roles").Where(condition, args...).Count(&count).Error
+	err := r.scoped(ctx).Model(&model.Account{}).Preload("Roles").Where(condition, args...).Count(&count).Error
 	return count, err
 } 
 
 //查询软删除的账户
 func (r *AccountRepositoryImpl) FindDeletedAccounts(ctx context.Context) ([]*model.Account, error) {
 	var accounts []*model.Account
-	err := r.db.WithContext(ctx).Preload("Roles").Unscoped().Where("deleted_at IS NOT NULL").Find(&accounts).Error
+	err := r.scoped(ctx).Preload("Roles").Unscoped().Where("deleted_at IS NOT NULL").Find(&accounts).Error
 	return accounts, err
 }
 
 //查询软删除的账户数量
 func (r *AccountRepositoryImpl) CountDeletedAccounts(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&model.Account{}).Unscoped().Where("deleted_at IS NOT NULL").Count(&count).Error
+	err := r.scoped(ctx).Model(&model.Account{}).Unscoped().Where("deleted_at IS NOT NULL").Count(&count).Error
 	return count, err
 }
 
 //恢复账户
 func (r *AccountRepositoryImpl) RestoreAccount(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Model(&model.Account{}).Unscoped().Where("id = ?", id).Update("deleted_at", nil).Error
+	result := r.scoped(ctx).Model(&model.Account{}).Unscoped().Where("id = ?", id).Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
 }
\ No newline at end of file
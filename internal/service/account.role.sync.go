@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go_casbin/internal/logger"
+	"go_casbin/internal/model"
+	"go_casbin/pkg/casbin"
+	"go_casbin/pkg/database"
+	"sort"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// 账户角色(account_roles)与Casbin g策略的同步：主体为账户ID，角色为角色名，启用多租户时域为账户的租户
+// 只管理角色表中存在的角色，直接配置给用户的其他g策略和限时授权添加的g策略不在同步范围内
+
+// AccountSubject 账户在Casbin中的主体
+func AccountSubject(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// accountRoleLinks 账户角色对应的g策略，启用多租户且账户没有租户时不生成
+func accountRoleLinks(enforcer *casbin.CasbinEnforcer, account *model.Account, roles []model.Role) []casbin.RoleLink {
+	if enforcer.IsDomainEnabled() && account.TenantID == "" {
+		return nil
+	}
+	links := make([]casbin.RoleLink, 0, len(roles))
+	for _, role := range roles {
+		link := casbin.RoleLink{User: AccountSubject(account.ID), Role: role.Name}
+		if enforcer.IsDomainEnabled() {
+			link.Domain = account.TenantID
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// currentRoles 事务内账户当前的角色，Replace传入的角色可能只有ID
+func currentRoles(tx *gorm.DB, account *model.Account) ([]model.Role, error) {
+	var roles []model.Role
+	err := tx.Model(account).Association("Roles").Find(&roles)
+	return roles, err
+}
+
+// roleLinkOp 已执行的g策略变更
+type roleLinkOp struct {
+	link  casbin.RoleLink
+	added bool
+}
+
+// roleLinkSync 一次账户操作中的g策略变更
+// 事务内只由plan计算差异，事务提交后才写g策略：策略存储与业务库是同一个sqlite库时，事务未结束就写策略会等待事务持有的写锁
+// 写g策略失败时按相反顺序撤销已执行的变更，再执行undo恢复已提交的账户数据
+type roleLinkSync struct {
+	enforcer *casbin.CasbinEnforcer
+	remove   []casbin.RoleLink
+	add      []casbin.RoleLink
+	applied  []roleLinkOp
+	undo     func(tx *gorm.DB) error
+}
+
+func newRoleLinkSync() *roleLinkSync {
+	return &roleLinkSync{enforcer: casbin.GetCasbinInstance()}
+}
+
+// plan 计算要删除的旧角色g策略和要添加的新角色g策略，undo在写g策略失败时恢复本次提交的数据
+func (s *roleLinkSync) plan(account *model.Account, oldAccount *model.Account, oldRoles, newRoles []model.Role, undo func(tx *gorm.DB) error) {
+	s.undo = undo
+	if s.enforcer == nil {
+		return
+	}
+	var oldLinks []casbin.RoleLink
+	if oldAccount != nil {
+		oldLinks = accountRoleLinks(s.enforcer, oldAccount, oldRoles)
+	}
+	s.add = accountRoleLinks(s.enforcer, account, newRoles)
+	keep := make(map[casbin.RoleLink]bool, len(s.add))
+	for _, link := range s.add {
+		keep[link] = true
+	}
+	for _, link := range oldLinks {
+		if !keep[link] {
+			s.remove = append(s.remove, link)
+		}
+	}
+}
+
+// run 在事务中执行fn，提交后写g策略；写g策略失败时补偿，返回写g策略的错误
+func (s *roleLinkSync) run(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	db := database.GetDB().WithContext(ctx)
+	if err := db.Transaction(fn); err != nil {
+		return err
+	}
+	err := s.apply()
+	if err == nil {
+		return nil
+	}
+	s.compensate()
+	if s.undo != nil {
+		if undoErr := db.Transaction(s.undo); undoErr != nil {
+			logger.ErrorWithErr("恢复账户数据失败，需执行reconcile-roles修复", undoErr)
+		}
+	}
+	return err
+}
+
+// apply 删除旧角色中不再拥有的g策略，添加新角色的g策略
+func (s *roleLinkSync) apply() error {
+	for _, link := range s.remove {
+		removed, err := s.enforcer.RemoveRoleLink(link)
+		if err != nil {
+			return err
+		}
+		if removed {
+			s.applied = append(s.applied, roleLinkOp{link: link})
+		}
+	}
+	for _, link := range s.add {
+		added, err := s.enforcer.AddRoleLink(link)
+		if err != nil {
+			return err
+		}
+		if added {
+			s.applied = append(s.applied, roleLinkOp{link: link, added: true})
+		}
+	}
+	return nil
+}
+
+// compensate 撤销已执行的g策略变更，补偿失败时记录日志，由reconcile-roles修复
+func (s *roleLinkSync) compensate() {
+	for i := len(s.applied) - 1; i >= 0; i-- {
+		op := s.applied[i]
+		var err error
+		if op.added {
+			_, err = s.enforcer.RemoveRoleLink(op.link)
+		} else {
+			_, err = s.enforcer.AddRoleLink(op.link)
+		}
+		if err != nil {
+			logger.ErrorWithErr("补偿账户角色g策略失败", err, logger.Field("link", op.link), logger.Bool("added", op.added))
+		}
+	}
+	s.applied = nil
+}
+
+// 账户角色与g策略的差异类型
+const (
+	RoleDriftMissing = "missing" // 账户有该角色，缺少g策略
+	RoleDriftExtra   = "extra"   // g策略在账户角色中不存在
+	RoleDriftOrphan  = "orphan"  // g策略的账户已不存在
+)
+
+// RoleDrift 一处差异
+type RoleDrift struct {
+	Kind     string          `json:"kind"`
+	Link     casbin.RoleLink `json:"link"`
+	Repaired bool            `json:"repaired"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// RoleReconcileReport 账户角色与g策略的对比结果
+type RoleReconcileReport struct {
+	Accounts int         `json:"accounts"` // 参与对比的账户数
+	Links    int         `json:"links"`    // 参与对比的g策略数
+	Skipped  []string    `json:"skipped"`  // 启用多租户但没有租户的账户，不生成g策略
+	Drifts   []RoleDrift `json:"drifts"`
+}
+
+// ReconcileAccountRoles 对比account_roles与g策略，repair为true时以account_roles为准修复差异
+// 按域加载策略时只对比已加载的域
+func (s *AccountServiceImpl) ReconcileAccountRoles(ctx context.Context, repair bool) (*RoleReconcileReport, error) {
+	enforcer := casbin.GetCasbinInstance()
+	if enforcer == nil {
+		return nil, errors.New("CasbinService未初始化")
+	}
+	db := database.GetDB().WithContext(ctx)
+
+	var accounts []model.Account
+	if err := db.Preload("Roles").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	var roleNames []string
+	if err := db.Model(&model.Role{}).Pluck("name", &roleNames).Error; err != nil {
+		return nil, err
+	}
+	managed := make(map[string]bool, len(roleNames))
+	for _, name := range roleNames {
+		managed[name] = true
+	}
+	held, err := enforcer.GrantHeldLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RoleReconcileReport{Accounts: len(accounts), Skipped: make([]string, 0), Drifts: make([]RoleDrift, 0)}
+	existing := make(map[string]bool, len(accounts))
+	expected := make(map[casbin.RoleLink]bool)
+	for i := range accounts {
+		account := &accounts[i]
+		existing[AccountSubject(account.ID)] = true
+		if enforcer.IsDomainEnabled() && account.TenantID == "" && len(account.Roles) > 0 {
+			report.Skipped = append(report.Skipped, AccountSubject(account.ID))
+			continue
+		}
+		if enforcer.IsDomainEnabled() && !enforcer.DomainLoaded(account.TenantID) {
+			// 按域加载时只对比已加载的域
+			continue
+		}
+		for _, link := range accountRoleLinks(enforcer, account, account.Roles) {
+			expected[link] = true
+		}
+	}
+	actual := make(map[casbin.RoleLink]bool)
+	for _, link := range enforcer.AllRoleLinks() {
+		if !managed[link.Role] || enforcer.IsDomainEnabled() && !enforcer.DomainLoaded(link.Domain) {
+			continue
+		}
+		if _, err := strconv.ParseUint(link.User, 10, 64); err != nil {
+			continue
+		}
+		actual[link] = true
+	}
+	report.Links = len(actual)
+
+	for link := range expected {
+		if !actual[link] {
+			report.Drifts = append(report.Drifts, RoleDrift{Kind: RoleDriftMissing, Link: link})
+		}
+	}
+	for link := range actual {
+		if expected[link] || held[link] {
+			continue
+		}
+		if existing[link.User] {
+			report.Drifts = append(report.Drifts, RoleDrift{Kind: RoleDriftExtra, Link: link})
+		} else {
+			report.Drifts = append(report.Drifts, RoleDrift{Kind: RoleDriftOrphan, Link: link})
+		}
+	}
+	sort.Slice(report.Drifts, func(i, j int) bool {
+		a, b := report.Drifts[i].Link, report.Drifts[j].Link
+		if a.User != b.User {
+			return a.User < b.User
+		}
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		return a.Role < b.Role
+	})
+
+	if repair {
+		for i := range report.Drifts {
+			drift := &report.Drifts[i]
+			var err error
+			if drift.Kind == RoleDriftMissing {
+				_, err = enforcer.AddRoleLink(drift.Link)
+			} else {
+				_, err = enforcer.RemoveRoleLink(drift.Link)
+			}
+			if err != nil {
+				drift.Error = err.Error()
+				continue
+			}
+			drift.Repaired = true
+		}
+	}
+	logger.Info("账户角色与g策略对比完成",
+		logger.Int("accounts", report.Accounts),
+		logger.Int("links", report.Links),
+		logger.Int("drifts", len(report.Drifts)),
+		logger.Bool("repair", repair),
+	)
+	return report, nil
+}
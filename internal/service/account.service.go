@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"go_casbin/internal/model"
 	"go_casbin/internal/repository/account"
-	"go_casbin/pkg/database"
+	"go_casbin/pkg/casbin"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AccountService interface {
@@ -19,6 +21,8 @@ type AccountService interface {
 	CreateAccountWithRoles(ctx context.Context, account *model.Account, roles []model.Role) error
 	UpdateAccountWithRoles(ctx context.Context, account *model.Account, roles []model.Role) error
 	DeleteAccountWithCleanup(ctx context.Context, id uint) error
+	// 对比并修复账户角色与Casbin g策略的差异
+	ReconcileAccountRoles(ctx context.Context, repair bool) (*RoleReconcileReport, error)
 }
 
 type AccountServiceImpl struct {
@@ -41,17 +45,53 @@ func (s *AccountServiceImpl) UpdateAccount(ctx context.Context, account *model.A
 	return s.accountRepository.Update(ctx, account)
 }
 
+// RemoveAccount 删除账户(软删除)，账户需在数据范围内，事务提交后删除账户角色的g策略
+// 保留角色关联，恢复账户时角色随之恢复
 func (s *AccountServiceImpl) RemoveAccount(ctx context.Context, id uint) error {
-	return s.accountRepository.Delete(ctx, id)
+	links := newRoleLinkSync()
+	return links.run(ctx, func(tx *gorm.DB) error {
+		current, err := scopedAccount(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(current).Error; err != nil {
+			return err
+		}
+		links.plan(current, current, current.Roles, nil, func(tx *gorm.DB) error {
+			return tx.Unscoped().Model(current).Update("deleted_at", nil).Error
+		})
+		return nil
+	})
 }
 
-func (s *AccountServiceImpl) ReplaceAccountRoles(ctx context.Context, account *model.Account, roles []model.Role) error {
-	return s.accountRepository.ReplaceAccountRoles(ctx, account, roles)
+// ReplaceAccountRoles 替换账户角色，账户需在数据范围内，事务提交后同步g策略
+func (s *AccountServiceImpl) ReplaceAccountRoles(ctx context.Context, acc *model.Account, roles []model.Role) error {
+	links := newRoleLinkSync()
+	return links.run(ctx, func(tx *gorm.DB) error {
+		current, err := scopedAccount(ctx, tx, acc.ID)
+		if err != nil {
+			return err
+		}
+		// Replace会改写current.Roles，先保留原角色
+		oldRoles := current.Roles
+		if err := tx.Model(current).Association("Roles").Replace(roles); err != nil {
+			return err
+		}
+		newRoles, err := currentRoles(tx, current)
+		if err != nil {
+			return err
+		}
+		links.plan(current, current, oldRoles, newRoles, func(tx *gorm.DB) error {
+			return tx.Model(current).Association("Roles").Replace(oldRoles)
+		})
+		return nil
+	})
 }
 
-// CreateAccountWithRoles 创建账户并分配角色（事务）
+// CreateAccountWithRoles 创建账户并分配角色（事务），提交后添加g策略，失败时删除新建的账户
 func (s *AccountServiceImpl) CreateAccountWithRoles(ctx context.Context, account *model.Account, roles []model.Role) error {
-	return database.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	links := newRoleLinkSync()
+	return links.run(ctx, func(tx *gorm.DB) error {
 		// 1. 创建账户
 		if err := tx.Create(account).Error; err != nil {
 			return err
@@ -63,47 +103,109 @@ func (s *AccountServiceImpl) CreateAccountWithRoles(ctx context.Context, account
 				return err
 			}
 		}
-		
+
+		// 3. 计算要添加的g策略，事务提交后写入
+		newRoles, err := currentRoles(tx, account)
+		if err != nil {
+			return err
+		}
+		links.plan(account, nil, nil, newRoles, func(tx *gorm.DB) error {
+			return deleteAccount(tx, account)
+		})
 		return nil
 	})
 }
 // 事务处理操作中,遇到错误会自动回滚
-// UpdateAccountWithRoles 更新账户和角色（事务）
+// UpdateAccountWithRoles 更新账户和角色（事务），账户需在数据范围内，提交后按新旧角色的差异更新g策略，失败时恢复原账户和角色
 func (s *AccountServiceImpl) UpdateAccountWithRoles(ctx context.Context, account *model.Account, roles []model.Role) error {
-	return database.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 1. 更新账户基本信息
+	links := newRoleLinkSync()
+	return links.run(ctx, func(tx *gorm.DB) error {
+		// 1. 按数据范围读取原账户和角色，租户变更时旧租户下的g策略也要删除；ID为空时Save按新建处理
+		var oldAccount *model.Account
+		if account.ID != 0 {
+			var err error
+			if oldAccount, err = scopedAccount(ctx, tx, account.ID); err != nil {
+				return err
+			}
+		}
+
+		// 2. 更新账户基本信息
 		if err := tx.Save(account).Error; err != nil {
 			return err
 		}
 		
-		// 2. 更新角色关联
+		// 3. 更新角色关联
 		if err := tx.Model(account).Association("Roles").Replace(roles); err != nil {
 			return err
 		}
-		
+
+		// 4. 计算g策略的差异，事务提交后写入
+		newRoles, err := currentRoles(tx, account)
+		if err != nil {
+			return err
+		}
+		if oldAccount == nil {
+			links.plan(account, nil, nil, newRoles, func(tx *gorm.DB) error {
+				return deleteAccount(tx, account)
+			})
+			return nil
+		}
+		links.plan(account, oldAccount, oldAccount.Roles, newRoles, func(tx *gorm.DB) error {
+			if err := tx.Omit(clause.Associations).Save(oldAccount).Error; err != nil {
+				return err
+			}
+			return tx.Model(oldAccount).Association("Roles").Replace(oldAccount.Roles)
+		})
 		return nil
 	})
 }
 
-// DeleteAccountWithCleanup 删除账户并清理相关数据（事务）
+// DeleteAccountWithCleanup 删除账户并清理相关数据（事务），账户需在数据范围内，提交后删除账户角色的g策略，失败时恢复账户和角色
 func (s *AccountServiceImpl) DeleteAccountWithCleanup(ctx context.Context, id uint) error {
-	return database.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 1. 先查找账户
-		var account model.Account
-		if err := tx.Preload("Roles").First(&account, id).Error; err != nil {
+	links := newRoleLinkSync()
+	return links.run(ctx, func(tx *gorm.DB) error {
+		// 1. 按数据范围查找账户
+		current, err := scopedAccount(ctx, tx, id)
+		if err != nil {
 			return err
 		}
 		
-		// 2. 清理角色关联
-		if err := tx.Model(&account).Association("Roles").Clear(); err != nil {
+		// 2. 清理角色关联，Clear会清空current.Roles，先保留原角色
+		oldRoles := current.Roles
+		if err := tx.Model(current).Association("Roles").Clear(); err != nil {
 			return err
 		}
 		
 		// 3. 删除账户
-		if err := tx.Delete(&account).Error; err != nil {
+		if err := tx.Delete(current).Error; err != nil {
 			return err
 		}
-		
+
+		// 4. 计算要删除的g策略，事务提交后写入
+		links.plan(current, current, oldRoles, nil, func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Model(current).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			return tx.Model(current).Association("Roles").Replace(oldRoles)
+		})
 		return nil
 	})
-}
\ No newline at end of file
+}
+
+// scopedAccount 事务内按数据范围读取账户及其角色，不在范围内时返回ErrAccountNotFound
+func scopedAccount(ctx context.Context, tx *gorm.DB, id uint) (*model.Account, error) {
+	var current model.Account
+	err := tx.Scopes(casbin.DataScopeQuery(ctx, account.DataScopeResource)).Preload("Roles").First(&current, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, account.ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &current, nil
+}
+
+// deleteAccount 物理删除账户及其角色关联，撤销新建账户时使用
+func deleteAccount(tx *gorm.DB, acc *model.Account) error {
+	return tx.Select("Roles").Unscoped().Delete(acc).Error
+}
@@ -0,0 +1,113 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_casbin/internal/dto"
+	casbinMiddleware "go_casbin/internal/middleware/casbin"
+	"go_casbin/pkg/casbin"
+	"go_casbin/pkg/jwt"
+)
+
+type AuthzService interface {
+	// 解释一次鉴权的结果，account为当前登录账户
+	Explain(ctx context.Context, account *jwt.Account, req dto.ExplainDTO) (*casbin.Explanation, error)
+
+	// 预演策略变更，报告结果发生变化的请求
+	DryRun(ctx context.Context, req dto.DryRunDTO) (*casbin.DryRunResult, error)
+
+	// 获取影子模式下各路由组的鉴权统计
+	ShadowStats(ctx context.Context) map[string]casbinMiddleware.ShadowStats
+
+	// 获取权限目录的全部分组，以及group下的权限(group为空时返回全部)
+	ListPermissions(ctx context.Context, group string) ([]string, []casbin.Permission)
+
+	// 校验策略，找出指向不存在路由的规则
+	ValidatePermissions(ctx context.Context) ([]casbin.CatalogIssue, error)
+
+	// 获取当前账户经角色继承后可执行的全部(obj, act)，prefix不为空时按obj前缀过滤
+	MyPermissions(ctx context.Context, account *jwt.Account, prefix string) ([]casbin.AllowedPermission, error)
+}
+
+type AuthzServiceImpl struct {
+	enforcer *casbin.CasbinEnforcer
+}
+
+func NewAuthzService() *AuthzServiceImpl {
+	return &AuthzServiceImpl{enforcer: casbin.GetCasbinInstance()}
+}
+
+func (s *AuthzServiceImpl) Explain(ctx context.Context, account *jwt.Account, req dto.ExplainDTO) (*casbin.Explanation, error) {
+	switch {
+	case req.UserID != "" && req.Method != "" && req.Path != "":
+		// 按HTTP请求解释时与CasbinAuth一致：用户本身及token中的角色都参与鉴权，域从token中取出
+		target := &jwt.Account{ID: req.UserID, Role: req.Roles}
+		if account != nil && account.ID == req.UserID && len(req.Roles) == 0 {
+			target = account
+		}
+		var domain string
+		if s.enforcer.IsDomainEnabled() {
+			domain = req.Domain
+			if domain == "" {
+				domain = casbinMiddleware.DomainOf(target)
+			}
+			if domain == "" {
+				return nil, errors.New("已启用多租户，domain不能为空")
+			}
+		}
+		var rvals []string
+		for _, v := range casbinMiddleware.RequestValues(domain, req.Path, req.Method) {
+			rvals = append(rvals, fmt.Sprint(v))
+		}
+		return s.enforcer.ExplainSubjects(s.enforcer.Strategy(), casbinMiddleware.Subjects(target), rvals...)
+	case req.Sub != "" && req.Obj != "" && req.Act != "":
+		if !s.enforcer.IsDomainEnabled() {
+			return s.enforcer.Explain(req.Sub, req.Obj, req.Act)
+		}
+		if req.Domain == "" {
+			return nil, errors.New("已启用多租户，domain不能为空")
+		}
+		return s.enforcer.Explain(req.Sub, req.Domain, req.Obj, req.Act)
+	default:
+		return nil, errors.New("请提供 sub/obj/act 或 user_id/method/path")
+	}
+}
+
+func toPolicyLines(lines []dto.PolicyLineDTO) []casbin.PolicyLine {
+	result := make([]casbin.PolicyLine, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, casbin.PolicyLine{Ptype: line.Ptype, Rule: line.Rule})
+	}
+	return result
+}
+
+func (s *AuthzServiceImpl) DryRun(ctx context.Context, req dto.DryRunDTO) (*casbin.DryRunResult, error) {
+	change := casbin.PolicyChange{Add: toPolicyLines(req.Add), Remove: toPolicyLines(req.Remove)}
+	return s.enforcer.DryRun(change, req.Requests)
+}
+
+func (s *AuthzServiceImpl) ShadowStats(ctx context.Context) map[string]casbinMiddleware.ShadowStats {
+	return casbinMiddleware.GetShadowStats()
+}
+
+func (s *AuthzServiceImpl) ListPermissions(ctx context.Context, group string) ([]string, []casbin.Permission) {
+	catalog := casbin.GetCatalog()
+	return catalog.Groups(), catalog.List(group)
+}
+
+func (s *AuthzServiceImpl) ValidatePermissions(ctx context.Context) ([]casbin.CatalogIssue, error) {
+	return s.enforcer.ValidateAgainstCatalog(casbin.GetCatalog())
+}
+
+func (s *AuthzServiceImpl) MyPermissions(ctx context.Context, account *jwt.Account, prefix string) ([]casbin.AllowedPermission, error) {
+	var domain string
+	if s.enforcer.IsDomainEnabled() {
+		// 与CasbinAuth相同，从token中取域
+		domain = casbinMiddleware.DomainOf(account)
+		if domain == "" {
+			return nil, errors.New("缺少租户信息")
+		}
+	}
+	return s.enforcer.AllowedPermissions(s.enforcer.Strategy(), casbinMiddleware.Subjects(account), domain, prefix)
+}
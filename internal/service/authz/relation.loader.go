@@ -0,0 +1,83 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_casbin/internal/repository/account"
+	workFlowService "go_casbin/internal/service/workFlow"
+	"go_casbin/pkg/casbin"
+	"strconv"
+)
+
+// 已注册加载器的资源类型
+const (
+	ResourceAccount          = "account"
+	ResourceWorkflowInstance = "workflow_instance"
+)
+
+// approver 审批步骤中的审批人
+type approver struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// RegisterRelationLoaders 注册账户和工作流实例的资源关系加载器
+func RegisterRelationLoaders(checker *casbin.RelationChecker) {
+	accountRepository := account.NewAccountRepository()
+	checker.Register(ResourceAccount, AccountRelationLoader(accountRepository))
+	checker.Register(ResourceWorkflowInstance, WorkflowInstanceRelationLoader(workFlowService.GetWorkFlowService()))
+}
+
+// AccountRelationLoader 账户的所有者是账户本人
+func AccountRelationLoader(repository account.AccountRepository) casbin.RelationLoader {
+	return func(ctx context.Context, id string) (casbin.Relations, error) {
+		accountID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("账户ID不正确: %s", id)
+		}
+		// 只用于判断所有者关系，不按数据范围过滤
+		acc, err := repository.FindByID(casbin.WithSystemPrincipal(ctx), uint(accountID))
+		if err != nil {
+			return nil, err
+		}
+		if acc == nil {
+			return nil, casbin.ErrResourceNotFound
+		}
+		return casbin.Relations{
+			casbin.RelationOwner: {strconv.FormatUint(uint64(acc.ID), 10)},
+		}, nil
+	}
+}
+
+// WorkflowInstanceRelationLoader 工作流实例当前步骤的审批人具有approver关系
+func WorkflowInstanceRelationLoader(service workFlowService.WorkFlowService) casbin.RelationLoader {
+	return func(ctx context.Context, id string) (casbin.Relations, error) {
+		// 审批人不一定在实例的数据范围内(如跨部门审批)，关系校验不按数据范围过滤
+		instance, err := service.GetWorkFlowInstance(casbin.WithSystemPrincipal(ctx), id)
+		if err != nil || instance == nil {
+			return nil, casbin.ErrResourceNotFound
+		}
+		steps := instance.Workflow.Steps
+		if len(steps) == 0 {
+			// 实例中未带出模版时按模版ID查询
+			if wf, err := service.GetWorkFlow(ctx, instance.WorkflowID); err == nil {
+				steps = wf.Steps
+			}
+		}
+		relations := casbin.Relations{}
+		if instance.Current < 0 || instance.Current >= len(steps) {
+			return relations, nil
+		}
+		var approvers []approver
+		if len(steps[instance.Current].Approvers) > 0 {
+			if err := json.Unmarshal(steps[instance.Current].Approvers, &approvers); err != nil {
+				return nil, fmt.Errorf("解析审批人失败: %w", err)
+			}
+		}
+		for _, a := range approvers {
+			relations[casbin.RelationApprover] = append(relations[casbin.RelationApprover], a.ID)
+		}
+		return relations, nil
+	}
+}
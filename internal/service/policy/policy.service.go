@@ -0,0 +1,358 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"go_casbin/internal/dto"
+	"go_casbin/pkg/casbin"
+	"strings"
+)
+
+type PolicyService interface {
+	// 按条件分页查询策略
+	ListPolicies(ctx context.Context, query dto.PolicyQuery) ([]dto.PolicyDTO, int64, error)
+
+	// 添加策略
+	AddPolicy(ctx context.Context, policy dto.PolicyDTO) (bool, error)
+
+	// 批量添加策略
+	AddPolicies(ctx context.Context, policies []dto.PolicyDTO) (bool, error)
+
+	// 删除策略
+	RemovePolicy(ctx context.Context, policy dto.PolicyDTO) (bool, error)
+
+	// 获取所有角色
+	ListRoles(ctx context.Context, domain string) ([]string, error)
+
+	// 给用户分配角色
+	AssignRole(ctx context.Context, assign dto.RoleAssignDTO) (bool, error)
+
+	// 移除用户的角色
+	UnassignRole(ctx context.Context, assign dto.RoleAssignDTO) (bool, error)
+
+	// 获取角色下的用户
+	ListRoleUsers(ctx context.Context, role, domain string) ([]string, error)
+
+	// 获取鉴权缓存命中统计
+	CacheStats(ctx context.Context) (*casbin.CacheStats, error)
+
+	// 检查策略：重复规则、没有用户的角色、没有权限的角色、角色继承环、被通配规则覆盖的规则
+	LintPolicies(ctx context.Context) ([]casbin.LintIssue, error)
+
+	// 导出全部策略
+	ExportPolicies(ctx context.Context, query dto.PolicyTransferQuery) ([]byte, casbin.Format, error)
+
+	// 导入策略
+	ImportPolicies(ctx context.Context, query dto.PolicyTransferQuery, data []byte) (*casbin.ImportResult, error)
+
+	// 保存当前策略为快照
+	CreateSnapshot(ctx context.Context, operator string, req dto.SnapshotCreateDTO) (*casbin.PolicySnapshot, error)
+
+	// 分页查询快照
+	ListSnapshots(ctx context.Context, query dto.SnapshotQuery) ([]casbin.PolicySnapshot, int64, error)
+
+	// 对比两个快照
+	DiffSnapshots(ctx context.Context, query dto.SnapshotDiffQuery) (*casbin.SnapshotDiff, error)
+
+	// 回滚到指定快照，返回回滚前的自动备份
+	RollbackSnapshot(ctx context.Context, operator string, id uint) (*casbin.PolicySnapshot, error)
+
+	// 获取全部数据范围策略
+	ListDataScopes(ctx context.Context) ([]dto.DataScopeDTO, error)
+
+	// 设置数据范围，已存在时覆盖
+	SetDataScope(ctx context.Context, req dto.DataScopeDTO) (bool, error)
+
+	// 删除数据范围
+	RemoveDataScope(ctx context.Context, req dto.DataScopeDTO) (bool, error)
+
+	// 获取全部字段权限策略
+	ListFieldPolicies(ctx context.Context) ([]dto.FieldPolicyDTO, error)
+
+	// 授予字段权限
+	AddFieldPolicy(ctx context.Context, req dto.FieldPolicyDTO) (bool, error)
+
+	// 收回字段权限
+	RemoveFieldPolicy(ctx context.Context, req dto.FieldPolicyDTO) (bool, error)
+
+	// 分页查询限时角色授权
+	ListRoleGrants(ctx context.Context, query dto.RoleGrantQuery) ([]casbin.RoleGrant, int64, error)
+
+	// 创建限时角色授权
+	GrantRole(ctx context.Context, operator string, req dto.RoleGrantDTO) (*casbin.RoleGrant, error)
+
+	// 撤销限时角色授权
+	RevokeRoleGrant(ctx context.Context, operator string, id uint) (*casbin.RoleGrant, error)
+}
+
+type PolicyServiceImpl struct {
+	enforcer *casbin.CasbinEnforcer
+}
+
+func NewPolicyService() *PolicyServiceImpl {
+	return &PolicyServiceImpl{enforcer: casbin.GetCasbinInstance()}
+}
+
+var errDomainRequired = errors.New("已启用多租户，domain不能为空")
+
+// toRule 将DTO转换为casbin规则，启用多租户时规则为 (sub, dom, obj, act)
+func (s *PolicyServiceImpl) toRule(policy dto.PolicyDTO) ([]string, error) {
+	if s.enforcer.IsDomainEnabled() {
+		if policy.Domain == "" {
+			return nil, errDomainRequired
+		}
+		return []string{policy.Sub, policy.Domain, policy.Obj, policy.Act}, nil
+	}
+	return []string{policy.Sub, policy.Obj, policy.Act}, nil
+}
+
+// fromRule 将casbin规则转换为DTO
+func (s *PolicyServiceImpl) fromRule(rule []string) dto.PolicyDTO {
+	var policy dto.PolicyDTO
+	fields := []*string{&policy.Sub, &policy.Obj, &policy.Act}
+	if s.enforcer.IsDomainEnabled() {
+		fields = []*string{&policy.Sub, &policy.Domain, &policy.Obj, &policy.Act}
+	}
+	for i, field := range fields {
+		if i < len(rule) {
+			*field = rule[i]
+		}
+	}
+	return policy
+}
+
+func (s *PolicyServiceImpl) ListPolicies(ctx context.Context, query dto.PolicyQuery) ([]dto.PolicyDTO, int64, error) {
+	filter := []string{query.Sub, query.Obj, query.Act}
+	if s.enforcer.IsDomainEnabled() {
+		filter = []string{query.Sub, query.Domain, query.Obj, query.Act}
+	}
+	rules := s.enforcer.GetFilteredPolicy(0, filter...)
+	total := int64(len(rules))
+
+	query.Normalize()
+	offset := (query.Page - 1) * query.PageSize
+	if offset > len(rules) {
+		return []dto.PolicyDTO{}, total, nil
+	}
+	end := offset + query.PageSize
+	if end > len(rules) {
+		end = len(rules)
+	}
+
+	policies := make([]dto.PolicyDTO, 0, end-offset)
+	for _, rule := range rules[offset:end] {
+		policies = append(policies, s.fromRule(rule))
+	}
+	return policies, total, nil
+}
+
+func (s *PolicyServiceImpl) AddPolicy(ctx context.Context, policy dto.PolicyDTO) (bool, error) {
+	rule, err := s.toRule(policy)
+	if err != nil {
+		return false, err
+	}
+	return s.enforcer.AddPolicies([][]string{rule})
+}
+
+func (s *PolicyServiceImpl) AddPolicies(ctx context.Context, policies []dto.PolicyDTO) (bool, error) {
+	rules := make([][]string, 0, len(policies))
+	for _, policy := range policies {
+		rule, err := s.toRule(policy)
+		if err != nil {
+			return false, err
+		}
+		rules = append(rules, rule)
+	}
+	return s.enforcer.AddPolicies(rules)
+}
+
+func (s *PolicyServiceImpl) RemovePolicy(ctx context.Context, policy dto.PolicyDTO) (bool, error) {
+	rule, err := s.toRule(policy)
+	if err != nil {
+		return false, err
+	}
+	return s.enforcer.RemovePolicies([][]string{rule})
+}
+
+func (s *PolicyServiceImpl) ListRoles(ctx context.Context, domain string) ([]string, error) {
+	if s.enforcer.IsDomainEnabled() && domain != "" {
+		return s.enforcer.GetAllRolesByDomain(domain), nil
+	}
+	return s.enforcer.GetAllRoles(), nil
+}
+
+func (s *PolicyServiceImpl) AssignRole(ctx context.Context, assign dto.RoleAssignDTO) (bool, error) {
+	if s.enforcer.IsDomainEnabled() {
+		if assign.Domain == "" {
+			return false, errDomainRequired
+		}
+		return s.enforcer.AddRoleForUserInDomain(assign.User, assign.Role, assign.Domain)
+	}
+	return s.enforcer.AddRoleForUser(assign.User, assign.Role)
+}
+
+func (s *PolicyServiceImpl) UnassignRole(ctx context.Context, assign dto.RoleAssignDTO) (bool, error) {
+	if s.enforcer.IsDomainEnabled() {
+		if assign.Domain == "" {
+			return false, errDomainRequired
+		}
+		return s.enforcer.DeleteRoleForUserInDomain(assign.User, assign.Role, assign.Domain)
+	}
+	return s.enforcer.DeleteRoleForUser(assign.User, assign.Role)
+}
+
+func (s *PolicyServiceImpl) ListRoleUsers(ctx context.Context, role, domain string) ([]string, error) {
+	if s.enforcer.IsDomainEnabled() {
+		if domain == "" {
+			return nil, errDomainRequired
+		}
+		return s.enforcer.GetUsersForRoleInDomain(role, domain), nil
+	}
+	return s.enforcer.GetUsersForRole(role), nil
+}
+
+func (s *PolicyServiceImpl) CacheStats(ctx context.Context) (*casbin.CacheStats, error) {
+	cache := s.enforcer.GetDecisionCache()
+	if cache == nil {
+		return nil, errors.New("未启用鉴权缓存")
+	}
+	stats := cache.Stats()
+	return &stats, nil
+}
+
+func (s *PolicyServiceImpl) LintPolicies(ctx context.Context) ([]casbin.LintIssue, error) {
+	return s.enforcer.LintPolicy()
+}
+
+func (s *PolicyServiceImpl) ExportPolicies(ctx context.Context, query dto.PolicyTransferQuery) ([]byte, casbin.Format, error) {
+	format, err := casbin.ParseFormat(query.Format)
+	if err != nil {
+		return nil, "", err
+	}
+	lines, err := s.enforcer.ExportPolicies()
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := casbin.EncodePolicies(format, lines)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, format, nil
+}
+
+func (s *PolicyServiceImpl) ImportPolicies(ctx context.Context, query dto.PolicyTransferQuery, data []byte) (*casbin.ImportResult, error) {
+	format, err := casbin.ParseFormat(query.Format)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := casbin.ParseImportMode(query.Mode)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := casbin.DecodePolicies(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("导入的策略为空")
+	}
+	return s.enforcer.ImportPolicies(lines, mode)
+}
+
+func (s *PolicyServiceImpl) CreateSnapshot(ctx context.Context, operator string, req dto.SnapshotCreateDTO) (*casbin.PolicySnapshot, error) {
+	return s.enforcer.CreateSnapshot(operator, req.Comment)
+}
+
+func (s *PolicyServiceImpl) ListSnapshots(ctx context.Context, query dto.SnapshotQuery) ([]casbin.PolicySnapshot, int64, error) {
+	query.Normalize()
+	return s.enforcer.ListSnapshots(query.Page, query.PageSize)
+}
+
+func (s *PolicyServiceImpl) DiffSnapshots(ctx context.Context, query dto.SnapshotDiffQuery) (*casbin.SnapshotDiff, error) {
+	return s.enforcer.DiffSnapshots(query.From, query.To)
+}
+
+func (s *PolicyServiceImpl) RollbackSnapshot(ctx context.Context, operator string, id uint) (*casbin.PolicySnapshot, error) {
+	return s.enforcer.Rollback(id, operator)
+}
+
+func (s *PolicyServiceImpl) ListDataScopes(ctx context.Context) ([]dto.DataScopeDTO, error) {
+	rules := s.enforcer.GetDataScopes()
+	scopes := make([]dto.DataScopeDTO, 0, len(rules))
+	for _, rule := range rules {
+		var scope dto.DataScopeDTO
+		if s.enforcer.IsDomainEnabled() && len(rule) >= 4 {
+			scope.Domain = rule[1]
+			rule = append(rule[:1:1], rule[2:]...)
+		}
+		if len(rule) < 3 {
+			continue
+		}
+		scope.Sub, scope.Resource, scope.Scope = rule[0], rule[1], rule[2]
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+func (s *PolicyServiceImpl) SetDataScope(ctx context.Context, req dto.DataScopeDTO) (bool, error) {
+	scope, err := casbin.ParseDataScope(req.Scope)
+	if err != nil {
+		return false, err
+	}
+	return s.enforcer.SetDataScope(req.Sub, req.Domain, req.Resource, scope)
+}
+
+func (s *PolicyServiceImpl) RemoveDataScope(ctx context.Context, req dto.DataScopeDTO) (bool, error) {
+	return s.enforcer.RemoveDataScope(req.Sub, req.Domain, req.Resource)
+}
+
+func (s *PolicyServiceImpl) ListFieldPolicies(ctx context.Context) ([]dto.FieldPolicyDTO, error) {
+	rules := s.enforcer.GetFieldPolicies()
+	policies := make([]dto.FieldPolicyDTO, 0, len(rules))
+	for _, rule := range rules {
+		var policy dto.FieldPolicyDTO
+		if s.enforcer.IsDomainEnabled() && len(rule) >= 4 {
+			policy.Domain = rule[1]
+			rule = append(rule[:1:1], rule[2:]...)
+		}
+		if len(rule) < 3 {
+			continue
+		}
+		policy.Sub, policy.Action = rule[0], rule[2]
+		policy.Object, policy.Field, _ = strings.Cut(rule[1], "#")
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+func (s *PolicyServiceImpl) AddFieldPolicy(ctx context.Context, req dto.FieldPolicyDTO) (bool, error) {
+	return s.enforcer.AddFieldPolicy(req.Sub, req.Domain, req.Object, req.Field, casbin.FieldAction(req.Action))
+}
+
+func (s *PolicyServiceImpl) RemoveFieldPolicy(ctx context.Context, req dto.FieldPolicyDTO) (bool, error) {
+	return s.enforcer.RemoveFieldPolicy(req.Sub, req.Domain, req.Object, req.Field, casbin.FieldAction(req.Action))
+}
+
+func (s *PolicyServiceImpl) ListRoleGrants(ctx context.Context, query dto.RoleGrantQuery) ([]casbin.RoleGrant, int64, error) {
+	query.Normalize()
+	return s.enforcer.ListGrants(query.User, query.Status, query.Page, query.PageSize)
+}
+
+func (s *PolicyServiceImpl) GrantRole(ctx context.Context, operator string, req dto.RoleGrantDTO) (*casbin.RoleGrant, error) {
+	grant := casbin.RoleGrantRequest{
+		User:     req.User,
+		Role:     req.Role,
+		Domain:   req.Domain,
+		EndAt:    req.EndAt,
+		Operator: operator,
+		Reason:   req.Reason,
+	}
+	if req.StartAt != nil {
+		grant.StartAt = *req.StartAt
+	}
+	return s.enforcer.GrantRole(grant)
+}
+
+func (s *PolicyServiceImpl) RevokeRoleGrant(ctx context.Context, operator string, id uint) (*casbin.RoleGrant, error) {
+	return s.enforcer.RevokeGrant(id, operator)
+}
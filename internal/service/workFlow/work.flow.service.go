@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	workflow "go_casbin/internal/model/workFlow"
+	"go_casbin/pkg/casbin"
 	"sort"
 	"strconv"
 )
@@ -57,6 +58,30 @@ type MockWorkFlowService struct {
 	nextWorkflowID uint64
 }
 
+var defaultWorkFlowService = NewWorkFlowServiceImpl()
+
+// DataScopeResource 工作流实例在数据范围策略(p3)中的资源类型
+const DataScopeResource = "workflow_instance"
+
+func init() {
+	casbin.RegisterDataScopeColumns(DataScopeResource, casbin.DataScopeColumns{
+		Tenant:     "tenant_id",
+		Department: "department_id",
+		Owner:      "created_by",
+	})
+}
+
+// visible 实例是否在请求上下文中主体的数据范围内，与账户仓储的DataScopeQuery规则一致
+func visible(ctx context.Context, inst *workflow.WorkflowInstance) bool {
+	row := casbin.DataScopeRow{Tenant: inst.TenantID, Department: inst.DepartmentID, Owner: inst.CreatedBy}
+	return casbin.DataScopeAllows(ctx, DataScopeResource, row)
+}
+
+// GetWorkFlowService 获取共享的工作流服务，控制器与资源关系加载器使用同一份数据
+func GetWorkFlowService() WorkFlowService {
+	return defaultWorkFlowService
+}
+
 func NewWorkFlowServiceImpl() *MockWorkFlowService {
 	return &MockWorkFlowService{
 		workflows:      make(map[uint]*workflow.WorkFlow),
@@ -114,19 +139,33 @@ func (m *MockWorkFlowService) ListWorkFlows(ctx context.Context, limit, offset i
 }
 
 func (m *MockWorkFlowService) CreateWorkFlowInstance(ctx context.Context, inst *workflow.WorkflowInstance) error {
+	// 发起人、租户、部门未指定时取自请求上下文中的主体
+	if p, ok := casbin.PrincipalFrom(ctx); ok {
+		if inst.CreatedBy == "" {
+			inst.CreatedBy = p.UserID
+		}
+		if inst.TenantID == "" {
+			inst.TenantID = p.TenantID
+		}
+		if inst.DepartmentID == "" {
+			inst.DepartmentID = p.DepartmentID
+		}
+	}
 	m.instances[strconv.FormatInt(inst.ID, 10)] = inst	
 	return nil
 }
 
+// GetWorkFlowInstance 不在当前主体数据范围内的实例按不存在处理
 func (m *MockWorkFlowService) GetWorkFlowInstance(ctx context.Context, id string) (*workflow.WorkflowInstance, error) {
-	if inst, ok := m.instances[id]; ok {
+	if inst, ok := m.instances[id]; ok && visible(ctx, inst) {
 		return inst, nil
 	}
 	return nil, fmt.Errorf("instance not found")
 }
 
+// UpdateWorkFlowInstance 不在当前主体数据范围内的实例按不存在处理
 func (m *MockWorkFlowService) UpdateWorkFlowInstance(ctx context.Context, id string, inst *workflow.WorkflowInstance) error {
-	if _, ok := m.instances[id]; !ok {
+	if current, ok := m.instances[id]; !ok || !visible(ctx, current) {
 		return fmt.Errorf("instance not found")
 	}
 	inst.ID,_ = strconv.ParseInt(id, 10, 64)
@@ -134,8 +173,9 @@ func (m *MockWorkFlowService) UpdateWorkFlowInstance(ctx context.Context, id str
 	return nil
 }
 
+// DeleteWorkFlowInstance 不在当前主体数据范围内的实例按不存在处理
 func (m *MockWorkFlowService) DeleteWorkFlowInstance(ctx context.Context, id string) error {
-	if _, ok := m.instances[id]; !ok {
+	if current, ok := m.instances[id]; !ok || !visible(ctx, current) {
 		return fmt.Errorf("instance not found")
 	}
 	delete(m.instances, id)
@@ -145,7 +185,9 @@ func (m *MockWorkFlowService) DeleteWorkFlowInstance(ctx context.Context, id str
 func (m *MockWorkFlowService) ListWorkFlowInstances(ctx context.Context) ([]*workflow.WorkflowInstance, error) {
 	values := make([]*workflow.WorkflowInstance, 0, len(m.instances))
 	for _, v := range m.instances {
-		values = append(values, v)
+		if visible(ctx, v) {
+			values = append(values, v)
+		}
 	}
 	sort.Slice(values, func(i, j int) bool { return values[i].ID < values[j].ID })
 
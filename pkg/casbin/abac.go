@@ -0,0 +1,126 @@
+package casbin
+
+import (
+	"errors"
+	"go_casbin/internal/logger"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// ABAC使用模型中的第二组定义(r2、p2、e2、m2)，与RBAC策略共用存储、watcher和导入导出
+// 策略格式：p2, 主体(用户、角色或*), 资源路径(keyMatch2), 操作(或*), 属性规则
+// 例如：p2, *, /api/v1/accounts/:id, PUT, r2.obj.Owner == r2.sub.ID && r2.sub.IsVerified == true
+const (
+	ABACRequestDefinition = "sub, obj, act, env"
+	ABACPolicyDefinition  = "sub, obj, act, rule"
+	ABACPolicyEffect      = "some(where (p.eft == allow))"
+	ABACMatcher           = `hasSubject(r2.sub, p2.sub) && pathMatch(r2.obj.Path, p2.obj) && (r2.act == p2.act || p2.act == "*") && eval(p2.rule)`
+)
+
+// Subject ABAC主体属性
+type Subject struct {
+	ID         string   // 用户ID
+	Username   string   // 用户名
+	Roles      []string // token中的角色
+	Domain     string   // 域，启用多租户时有效
+	Platform   string   // 平台
+	Status     int      // 账户状态
+	IsVerified bool     // 是否验证
+	IsLocked   bool     // 是否锁定
+}
+
+// Resource ABAC资源属性
+type Resource struct {
+	Type   string            // 资源类型
+	ID     string            // 资源ID
+	Path   string            // 路由模板或请求路径，与策略中的资源做pathMatch匹配
+	Owner  string            // 资源所有者ID
+	Domain string            // 资源所属域
+	Attrs  map[string]string // 其他属性
+}
+
+// Environment ABAC环境属性
+type Environment struct {
+	Time    time.Time // 请求时间
+	Hour    int       // 小时(0-23)
+	Weekday int       // 星期(0为周日)
+	IP      string    // 客户端IP，可在规则中使用ipMatch
+}
+
+// NewEnvironment 根据时间和客户端IP构造环境属性
+func NewEnvironment(now time.Time, ip string) Environment {
+	return Environment{Time: now, Hour: now.Hour(), Weekday: int(now.Weekday()), IP: ip}
+}
+
+// ensureABACModel 模型中没有r2定义时补充ABAC定义
+func ensureABACModel(m model.Model) {
+	if _, ok := m["r"]["r2"]; ok {
+		return
+	}
+	m.AddDef("r", "r2", ABACRequestDefinition)
+	m.AddDef("p", "p2", ABACPolicyDefinition)
+	m.AddDef("e", "e2", ABACPolicyEffect)
+	m.AddDef("m", "m2", ABACMatcher)
+}
+
+// IsABACEnabled 是否启用ABAC
+func (c *CasbinEnforcer) IsABACEnabled() bool {
+	return c.abacEnable
+}
+
+// hasSubjectOf 返回绑定到执行器e的hasSubject匹配器函数：主体本身、token中的角色或经由g策略继承的角色与策略主体一致
+// 在Enforce持有的锁内调用，只能使用不加锁的角色管理器
+func (c *CasbinEnforcer) hasSubjectOf(e casbin.IEnforcer) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return false, errors.New("hasSubject需要2个参数")
+		}
+		sub, ok := args[0].(Subject)
+		if !ok {
+			return false, errors.New("hasSubject的第一个参数必须是Subject")
+		}
+		target, _ := args[1].(string)
+		if target == "*" || target == sub.ID {
+			return true, nil
+		}
+		var domain []string
+		if c.domainEnable {
+			domain = []string{sub.Domain}
+		}
+		rm := e.GetRoleManager()
+		for _, name := range append([]string{sub.ID}, sub.Roles...) {
+			if name == target {
+				return true, nil
+			}
+			if name == "" {
+				continue
+			}
+			if linked, err := rm.HasLink(name, target, domain...); err == nil && linked {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// EnforceABAC 按主体、资源、环境属性鉴权，结果不缓存
+func (c *CasbinEnforcer) EnforceABAC(sub Subject, obj Resource, act string, env Environment) (bool, error) {
+	if !c.abacEnable {
+		return false, errors.New("未启用ABAC")
+	}
+	ok, err := c.enforcer().Enforce(casbin.NewEnforceContext("2"), sub, obj, act, env)
+	if err != nil {
+		logger.ErrorWithErr("Casbin属性鉴权失败", err, logger.String("sub", sub.ID), logger.String("obj", obj.Path), logger.String("act", act))
+	}
+	return ok, err
+}
+
+// EnforceABACEx 属性鉴权并返回命中的策略
+func (c *CasbinEnforcer) EnforceABACEx(sub Subject, obj Resource, act string, env Environment) (bool, []string, error) {
+	if !c.abacEnable {
+		return false, nil, errors.New("未启用ABAC")
+	}
+	return c.enforcer().EnforceEx(casbin.NewEnforceContext("2"), sub, obj, act, env)
+}
@@ -0,0 +1,283 @@
+package casbin
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"gorm.io/gorm"
+)
+
+// CasbinRule 策略表，表结构与gorm-adapter的casbin_rule保持一致，已有数据可直接使用
+type CasbinRule struct {
+	PType string `gorm:"size:100;index"`
+	V0    string `gorm:"size:100"`
+	V1    string `gorm:"size:100"`
+	V2    string `gorm:"size:100"`
+	V3    string `gorm:"size:100"`
+	V4    string `gorm:"size:100"`
+	V5    string `gorm:"size:100"`
+}
+
+func (CasbinRule) TableName() string {
+	return "casbin_rule"
+}
+
+// GormAdapter 基于gorm v2的策略存储，SavePolicy等整体写入在事务中执行
+// 不使用 casbin/gorm-adapter v1：它基于jinzhu/gorm v1并自建连接，无法复用项目的 *gorm.DB，
+// 导入策略时也无法在同一个事务中清空并写入(见savePolicyTx)，按域过滤加载同样需要自定义查询
+type GormAdapter struct {
+	db       *gorm.DB
+	filtered atomic.Bool // 最近一次按域过滤加载，此时执行器拒绝SavePolicy
+}
+
+var (
+	_ persist.Adapter         = (*GormAdapter)(nil)
+	_ persist.BatchAdapter    = (*GormAdapter)(nil)
+	_ persist.FilteredAdapter = (*GormAdapter)(nil)
+)
+
+// DomainFilter 按域过滤加载策略的条件
+type DomainFilter struct {
+	Domains []string // 只加载这些域的策略
+	Shared  bool     // 同时加载没有域字段的策略，如ABAC的p2
+}
+
+// NewGormAdapter 创建策略存储并自动迁移casbin_rule表
+func NewGormAdapter(db *gorm.DB) (*GormAdapter, error) {
+	if err := db.AutoMigrate(&CasbinRule{}); err != nil {
+		return nil, err
+	}
+	return &GormAdapter{db: db}, nil
+}
+
+// DB 获取策略存储使用的数据库连接
+func (a *GormAdapter) DB() *gorm.DB {
+	return a.db
+}
+
+func newCasbinRule(ptype string, rule []string) CasbinRule {
+	line := CasbinRule{PType: ptype}
+	values := []*string{&line.V0, &line.V1, &line.V2, &line.V3, &line.V4, &line.V5}
+	for i, v := range rule {
+		if i >= len(values) {
+			break
+		}
+		*values[i] = v
+	}
+	return line
+}
+
+func (r CasbinRule) toArray() []string {
+	rule := []string{r.PType, r.V0, r.V1, r.V2, r.V3, r.V4, r.V5}
+	// 去掉末尾的空字段
+	for len(rule) > 1 && rule[len(rule)-1] == "" {
+		rule = rule[:len(rule)-1]
+	}
+	return rule
+}
+
+// ruleQuery 按规则的全部六个字段精确匹配，空字段匹配空值，表没有主键只能按字段匹配
+func ruleQuery(db *gorm.DB, line CasbinRule) *gorm.DB {
+	return db.Where("p_type = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ? AND v4 = ? AND v5 = ?",
+		line.PType, line.V0, line.V1, line.V2, line.V3, line.V4, line.V5)
+}
+
+// filteredQuery 按字段条件匹配，与casbin的RemoveFilteredPolicy一致，空字段匹配任意值
+func filteredQuery(db *gorm.DB, line CasbinRule) *gorm.DB {
+	db = db.Where("p_type = ?", line.PType)
+	columns := []string{"v0", "v1", "v2", "v3", "v4", "v5"}
+	values := []string{line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+	for i, v := range values {
+		if v != "" {
+			db = db.Where(columns[i]+" = ?", v)
+		}
+	}
+	return db
+}
+
+// LoadPolicy 加载全部策略
+func (a *GormAdapter) LoadPolicy(m model.Model) error {
+	if err := a.loadLines(a.db, m); err != nil {
+		return err
+	}
+	a.filtered.Store(false)
+	return nil
+}
+
+func (a *GormAdapter) loadLines(query *gorm.DB, m model.Model) error {
+	var lines []CasbinRule
+	if err := query.Find(&lines).Error; err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if err := persist.LoadPolicyArray(line.toArray(), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFilteredPolicy 按DomainFilter加载策略，filter为空时加载全部
+// 有域字段的策略类型(p的dom字段、g的第三个字段)按域过滤，模型中没有的策略类型不加载
+func (a *GormAdapter) LoadFilteredPolicy(m model.Model, filter interface{}) error {
+	var f *DomainFilter
+	switch v := filter.(type) {
+	case nil:
+		return a.LoadPolicy(m)
+	case DomainFilter:
+		f = &v
+	case *DomainFilter:
+		f = v
+	default:
+		return fmt.Errorf("不支持的策略过滤条件: %T", filter)
+	}
+	columns := []string{"v0", "v1", "v2", "v3", "v4", "v5"}
+	for _, sec := range []string{"p", "g"} {
+		for ptype := range m[sec] {
+			index := domainIndex(m, sec, ptype)
+			query := a.db.Where("p_type = ?", ptype)
+			switch {
+			case index < 0 && !f.Shared:
+				continue
+			case index >= len(columns):
+				continue
+			case index >= 0:
+				if len(f.Domains) == 0 {
+					continue
+				}
+				query = query.Where(columns[index]+" IN ?", f.Domains)
+			}
+			if err := a.loadLines(query, m); err != nil {
+				return err
+			}
+		}
+	}
+	a.filtered.Store(true)
+	return nil
+}
+
+// IsFiltered 最近一次是否按域过滤加载
+func (a *GormAdapter) IsFiltered() bool {
+	return a.filtered.Load()
+}
+
+// domainIndex 策略类型中域字段的位置：p按dom字段，g按第三个字段，没有域字段时返回-1
+func domainIndex(m model.Model, sec, ptype string) int {
+	assertion, ok := m[sec][ptype]
+	if !ok {
+		return -1
+	}
+	if sec == "g" {
+		if len(assertion.Tokens) >= 3 {
+			return 2
+		}
+		return -1
+	}
+	for i, token := range assertion.Tokens {
+		if token == ptype+"_dom" {
+			return i
+		}
+	}
+	return -1
+}
+
+// SavePolicy 在事务中用模型中的策略替换表中全部策略
+func (a *GormAdapter) SavePolicy(m model.Model) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		return savePolicyTx(tx, m)
+	})
+}
+
+// savePolicyTx 清空策略表并写入模型中的全部策略，调用方负责事务
+func savePolicyTx(tx *gorm.DB, m model.Model) error {
+	if err := tx.Where("1 = 1").Delete(&CasbinRule{}).Error; err != nil {
+		return err
+	}
+	lines := make([]CasbinRule, 0)
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range m[sec] {
+			for _, rule := range ast.Policy {
+				lines = append(lines, newCasbinRule(ptype, rule))
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(lines, 500).Error
+}
+
+// AddPolicy 添加一条策略
+func (a *GormAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	line := newCasbinRule(ptype, rule)
+	if a.IsFiltered() {
+		// 未加载的域在内存中查不到已有规则，写入前检查表中是否已存在，避免重复
+		exists, err := a.exists(line)
+		if err != nil || exists {
+			return err
+		}
+	}
+	return a.db.Create(&line).Error
+}
+
+// exists 表中是否已有完全相同的规则
+func (a *GormAdapter) exists(line CasbinRule) (bool, error) {
+	var count int64
+	err := ruleQuery(a.db.Model(&CasbinRule{}), line).Count(&count).Error
+	return count > 0, err
+}
+
+// RemovePolicy 删除一条策略
+func (a *GormAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return ruleQuery(a.db, newCasbinRule(ptype, rule)).Delete(&CasbinRule{}).Error
+}
+
+// AddPolicies 批量添加策略
+func (a *GormAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	lines := make([]CasbinRule, 0, len(rules))
+	for _, rule := range rules {
+		line := newCasbinRule(ptype, rule)
+		if a.IsFiltered() {
+			exists, err := a.exists(line)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return a.db.Create(&lines).Error
+}
+
+// RemovePolicies 批量删除策略
+func (a *GormAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		for _, rule := range rules {
+			if err := ruleQuery(tx, newCasbinRule(ptype, rule)).Delete(&CasbinRule{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveFilteredPolicy 按字段条件删除策略
+func (a *GormAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	if fieldIndex < 0 || fieldIndex+len(fieldValues) > 6 {
+		return errors.New("策略字段下标超出范围")
+	}
+	rule := make([]string, fieldIndex+len(fieldValues))
+	copy(rule[fieldIndex:], fieldValues)
+	return filteredQuery(a.db, newCasbinRule(ptype, rule)).Delete(&CasbinRule{}).Error
+}
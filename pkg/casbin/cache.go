@@ -0,0 +1,342 @@
+package casbin
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_casbin/internal/logger"
+	"go_casbin/pkg/redis"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DecisionCacheOptions 鉴权结果缓存配置
+type DecisionCacheOptions struct {
+	Capacity int                     // 本地LRU容量
+	TTL      time.Duration           // 缓存有效期
+	Redis    *redis.RedisServiceImpl // 可选的redis二级缓存，为空时只使用本地缓存
+	Prefix   string                  // redis key前缀
+}
+
+// CacheStats 缓存命中统计
+type CacheStats struct {
+	Hits          uint64  `json:"hits"`          // 本地命中次数
+	RedisHits     uint64  `json:"redis_hits"`    // redis命中次数
+	Misses        uint64  `json:"misses"`        // 未命中次数
+	Invalidations uint64  `json:"invalidations"` // 失效次数
+	Size          int     `json:"size"`          // 本地缓存条目数
+	Generation    int64   `json:"generation"`    // 当前缓存代数
+	HitRate       float64 `json:"hit_rate"`      // 命中率
+}
+
+// cachedDecision 单个主体的鉴权结果
+type cachedDecision struct {
+	Allowed bool     `json:"allowed"`
+	Policy  []string `json:"policy"`
+}
+
+type cacheEntry struct {
+	key      string
+	subject  string
+	value    cachedDecision
+	expireAt time.Time
+}
+
+// DecisionCache 鉴权结果缓存：本地LRU + 可选redis二级缓存
+// 本地缓存按主体建立索引，策略变更时只失效受影响的主体；
+// redis缓存的key带有全局代数，任何变更都使代数递增从而整体失效
+type DecisionCache struct {
+	mu        sync.Mutex
+	capacity  int
+	ttl       time.Duration
+	ll        *list.List
+	items     map[string]*list.Element
+	bySubject map[string]map[string]struct{}
+	epoch     uint64 // 本地失效次数，持有mu时读写
+
+	redis      *redis.RedisServiceImpl
+	prefix     string
+	generation atomic.Int64
+
+	hits          atomic.Uint64
+	redisHits     atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+// NewDecisionCache 创建鉴权结果缓存
+func NewDecisionCache(options DecisionCacheOptions) *DecisionCache {
+	if options.Capacity <= 0 {
+		options.Capacity = 10000
+	}
+	if options.TTL <= 0 {
+		options.TTL = 5 * time.Minute
+	}
+	if options.Prefix == "" {
+		options.Prefix = "casbin:decision"
+	}
+	d := &DecisionCache{
+		capacity:  options.Capacity,
+		ttl:       options.TTL,
+		ll:        list.New(),
+		items:     make(map[string]*list.Element),
+		bySubject: make(map[string]map[string]struct{}),
+		redis:     options.Redis,
+		prefix:    options.Prefix,
+	}
+	d.syncGeneration()
+	return d
+}
+
+// cacheVersion 鉴权前读取的缓存版本；写入时版本已变化，说明鉴权期间策略发生了变更，结果可能已过期
+type cacheVersion struct {
+	epoch      uint64
+	generation int64
+}
+
+// version 当前缓存版本，需在执行matcher之前读取
+func (d *DecisionCache) version() cacheVersion {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return cacheVersion{epoch: d.epoch, generation: d.generation.Load()}
+}
+
+// cacheKey 生成缓存key，只有全部请求参数都是字符串时才可缓存
+func cacheKey(rvals []interface{}) (string, bool) {
+	if len(rvals) == 0 {
+		return "", false
+	}
+	parts := make([]string, 0, len(rvals))
+	for _, v := range rvals {
+		s, ok := v.(string)
+		if !ok {
+			return "", false
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "\x1f"), true
+}
+
+func (d *DecisionCache) redisKey(generation int64, key string) string {
+	return fmt.Sprintf("%s:%d:%s", d.prefix, generation, key)
+}
+
+func (d *DecisionCache) generationKey() string {
+	return d.prefix + ":generation"
+}
+
+// get 查询缓存，本地未命中时查询redis；redis命中的结果按version回填本地缓存
+func (d *DecisionCache) get(key string, version cacheVersion) (cachedDecision, bool) {
+	d.mu.Lock()
+	if el, ok := d.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		if time.Now().Before(entry.expireAt) {
+			d.ll.MoveToFront(el)
+			d.mu.Unlock()
+			d.hits.Add(1)
+			return entry.value, true
+		}
+		d.removeElement(el)
+	}
+	d.mu.Unlock()
+
+	if d.redis != nil {
+		if payload, err := d.redis.Get(context.Background(), d.redisKey(version.generation, key)); err == nil {
+			var value cachedDecision
+			if json.Unmarshal([]byte(payload), &value) == nil {
+				d.redisHits.Add(1)
+				d.setLocal(key, value, version)
+				return value, true
+			}
+		}
+	}
+	d.misses.Add(1)
+	return cachedDecision{}, false
+}
+
+// set 写入本地缓存和redis，version为执行matcher之前读取的版本，期间发生过失效时丢弃结果
+func (d *DecisionCache) set(key string, value cachedDecision, version cacheVersion) {
+	if !d.setLocal(key, value, version) {
+		return
+	}
+	if d.redis != nil {
+		payload, _ := json.Marshal(value)
+		if err := d.redis.Set(context.Background(), d.redisKey(version.generation, key), payload, d.ttl); err != nil {
+			logger.ErrorWithErr("写入redis鉴权缓存失败", err)
+		}
+	}
+}
+
+// setLocal 写入本地缓存，版本已变化时不写入并返回false
+func (d *DecisionCache) setLocal(key string, value cachedDecision, version cacheVersion) bool {
+	subject := strings.SplitN(key, "\x1f", 2)[0]
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.epoch != version.epoch {
+		return false
+	}
+	if el, ok := d.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.value = value
+		entry.expireAt = time.Now().Add(d.ttl)
+		d.ll.MoveToFront(el)
+		return true
+	}
+	el := d.ll.PushFront(&cacheEntry{key: key, subject: subject, value: value, expireAt: time.Now().Add(d.ttl)})
+	d.items[key] = el
+	if d.bySubject[subject] == nil {
+		d.bySubject[subject] = make(map[string]struct{})
+	}
+	d.bySubject[subject][key] = struct{}{}
+	for d.ll.Len() > d.capacity {
+		d.removeElement(d.ll.Back())
+	}
+	return true
+}
+
+// removeElement 调用方需持有锁
+func (d *DecisionCache) removeElement(el *list.Element) {
+	entry := d.ll.Remove(el).(*cacheEntry)
+	delete(d.items, entry.key)
+	if keys, ok := d.bySubject[entry.subject]; ok {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(d.bySubject, entry.subject)
+		}
+	}
+}
+
+// InvalidateSubjects 失效指定主体的本地缓存，redis缓存整体换代
+func (d *DecisionCache) InvalidateSubjects(subjects ...string) {
+	d.mu.Lock()
+	d.epoch++
+	for _, subject := range subjects {
+		for key := range d.bySubject[subject] {
+			if el, ok := d.items[key]; ok {
+				d.removeElement(el)
+			}
+		}
+	}
+	d.mu.Unlock()
+	d.invalidations.Add(1)
+	d.bumpGeneration()
+}
+
+// InvalidateAll 清空全部缓存
+func (d *DecisionCache) InvalidateAll() {
+	d.mu.Lock()
+	d.epoch++
+	d.ll.Init()
+	d.items = make(map[string]*list.Element)
+	d.bySubject = make(map[string]map[string]struct{})
+	d.mu.Unlock()
+	d.invalidations.Add(1)
+	d.bumpGeneration()
+}
+
+// bumpGeneration 本地变更后递增代数，redis中的旧结果随之失效
+func (d *DecisionCache) bumpGeneration() {
+	if d.redis == nil {
+		d.generation.Add(1)
+		return
+	}
+	gen, err := d.redis.Incr(context.Background(), d.generationKey())
+	if err != nil {
+		logger.ErrorWithErr("递增鉴权缓存代数失败", err)
+		d.generation.Add(1)
+		return
+	}
+	d.generation.Store(gen)
+}
+
+// syncGeneration 启动时从redis读取当前代数，与其他实例共享redis缓存
+func (d *DecisionCache) syncGeneration() {
+	if d.redis == nil {
+		return
+	}
+	payload, err := d.redis.Get(context.Background(), d.generationKey())
+	if err != nil {
+		return
+	}
+	if gen, err := strconv.ParseInt(payload, 10, 64); err == nil {
+		d.generation.Store(gen)
+	}
+}
+
+// Stats 获取缓存命中统计
+func (d *DecisionCache) Stats() CacheStats {
+	d.mu.Lock()
+	size := d.ll.Len()
+	d.mu.Unlock()
+	stats := CacheStats{
+		Hits:          d.hits.Load(),
+		RedisHits:     d.redisHits.Load(),
+		Misses:        d.misses.Load(),
+		Invalidations: d.invalidations.Load(),
+		Size:          size,
+		Generation:    d.generation.Load(),
+	}
+	if total := stats.Hits + stats.RedisHits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits+stats.RedisHits) / float64(total)
+	}
+	return stats
+}
+
+// SetDecisionCache 启用鉴权结果缓存
+func (c *CasbinEnforcer) SetDecisionCache(cache *DecisionCache) {
+	c.cache = cache
+}
+
+// GetDecisionCache 获取鉴权结果缓存，未启用时为nil
+func (c *CasbinEnforcer) GetDecisionCache() *DecisionCache {
+	return c.cache
+}
+
+// invalidateRules 根据变更的规则失效受影响主体的缓存
+// p规则影响该主体及继承它的所有用户，g规则影响该用户及继承它的所有用户
+func (c *CasbinEnforcer) invalidateRules(sec string, rules ...[]string) {
+	if c.cache == nil {
+		return
+	}
+	domainIndex := 1
+	if sec == "g" {
+		domainIndex = 2
+	}
+	subjects := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule) == 0 {
+			continue
+		}
+		var domain []string
+		if c.domainEnable && len(rule) > domainIndex {
+			domain = []string{rule[domainIndex]}
+		}
+		subjects = append(subjects, rule[0])
+		users, err := c.enforcer().GetImplicitUsersForRole(rule[0], domain...)
+		if err != nil {
+			// 无法确定影响范围时整体失效
+			c.cache.InvalidateAll()
+			return
+		}
+		subjects = append(subjects, users...)
+	}
+	c.cache.InvalidateSubjects(subjects...)
+}
+
+// invalidateSubjects 失效指定主体的缓存
+func (c *CasbinEnforcer) invalidateSubjects(subjects []string) {
+	if c.cache != nil && len(subjects) > 0 {
+		c.cache.InvalidateSubjects(subjects...)
+	}
+}
+
+// invalidateAll 整体失效缓存
+func (c *CasbinEnforcer) invalidateAll() {
+	if c.cache != nil {
+		c.cache.InvalidateAll()
+	}
+}
@@ -1,16 +1,26 @@
 package casbin
 
 import (
+	"fmt"
 	"go_casbin/internal/config"
 	"go_casbin/internal/logger"
+	"go_casbin/pkg/database"
 	"go_casbin/pkg/path"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
-	gormadapter "github.com/casbin/gorm-adapter"
+	"github.com/casbin/casbin/v2/persist"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"gorm.io/gorm"
 )
 
+// defaultMemoryPolicyDB 内存数据库模式下未指定DataSource时策略表所在的库名
+const defaultMemoryPolicyDB = "casbin_policy"
+
 var (
 	CasbinService *CasbinEnforcer
 	once          sync.Once
@@ -18,46 +28,111 @@ var (
 
 // CasbinEnforcer Casbin执行器封装
 type CasbinEnforcer struct {
-	enforcer *casbin.Enforcer
-	adapter  *gormadapter.Adapter
-	model    *model.Model
+	active       atomic.Pointer[casbin.SyncedEnforcer] // 当前执行器，模型热加载时原子替换
+	reloadMu     sync.Mutex                            // 串行执行模型加载
+	swapMu       sync.RWMutex                          // 修改策略时持读锁，替换执行器时持写锁，修改不会落在被替换的执行器上
+	options      CasbinOptions                         // 初始化参数，模型热加载时复用
+	adapter      *GormAdapter                          // 数据库模式下的策略存储，文件模式为空
+	domainEnable bool            // 是否启用多租户(RBAC with domains)模型
+	abacEnable   bool            // 是否启用ABAC(模型中的r2/p2/m2)
+	cache        *DecisionCache  // 鉴权结果缓存，为空时不缓存
+	watcher      persist.Watcher // 策略同步watcher，为空时不广播
+	snapshotDB   *gorm.DB        // 策略快照所在数据库，为空时不支持快照
+	accessLog    *accessLog      // 最近的鉴权请求，用于预演策略变更
+	grants       *roleGrants     // 限时角色授权，为空时不支持
+	tenants      *tenantLoader   // 按域加载策略，为空时加载全部策略
 }
 type CasbinOptions struct {
-	Driver string
-	DataSource string
-	ModelPath string
+	Driver       string // file、mysql、postgres、sqlite、memory
+	DataSource   string // 策略文件路径或数据库DSN；sqlite为数据库文件路径，memory为内存库名
+	ModelPath    string
+	EnableDomain bool // 启用多租户模型，ModelPath为空时使用内置的RBACWithDomainsModel，否则使用RBACModel
+	EnableABAC   bool // 启用ABAC，模型中没有r2定义时自动补充
+	Domains      []string // 启用多租户时只加载这些域的策略(常驻)，需要数据库存储
+	LazyDomains  bool     // 启用多租户时其他域在首次请求时加载
+	Strategy     Strategy // 多主体鉴权结果合并策略，为空时为any-allow
 }
 
 // InitCasbin 初始化casbin服务
 func InitCasbin(options CasbinOptions) (initErr error) {
 	// once.Do(func() {
-	var enforcer *casbin.Enforcer
-	var adapter *gormadapter.Adapter
-	var model *model.Model
+	var enforcer *casbin.SyncedEnforcer
+	var adapter *GormAdapter
+	var m model.Model
 	var err error
 
+	tenants, err := newTenantLoaderFor(options)
+	if err != nil {
+		logger.ErrorWithErr("按域加载策略配置错误", err)
+		initErr = err
+		return
+	}
+
+	options.Strategy = ParseStrategy(string(options.Strategy))
+
 	// 根据driver类型选择不同的初始化方式
 	if options.Driver == "file" {
 		// 文件模式 - 使用项目根目录的绝对路径
-		modelPath, err := path.GetAbsolutePath(options.ModelPath)
+		m, err = newModel(options)
+		if err == nil {
+			err = checkStrategy(m, options.Strategy)
+		}
 		if err != nil {
-			logger.ErrorWithErr("获取项目根目录失败", err)
+			logger.ErrorWithErr("加载Casbin模型失败", err, logger.String("modelPath", options.ModelPath))
 			initErr = err
 			return
 		}
 
-		adapterPath, err := path.GetAbsolutePath(options.DataSource)
-		if err != nil {
-			logger.ErrorWithErr("获取项目根目录失败", err)
-			initErr = err
-			return
+		adapterPath := options.DataSource
+		if !filepath.IsAbs(adapterPath) {
+			absPath, absErr := path.GetAbsolutePath(adapterPath)
+			if absErr != nil {
+				logger.ErrorWithErr("获取项目根目录失败", absErr)
+				initErr = absErr
+				return
+			}
+			adapterPath = absPath
 		}
 
-		enforcer, err = casbin.NewEnforcer(modelPath, adapterPath)
+		enforcer, err = casbin.NewSyncedEnforcer(m, fileadapter.NewAdapter(adapterPath))
 	} else {
 		// 数据库模式
-		adapter := gormadapter.NewAdapter(options.Driver, options.DataSource)
-		enforcer, err = casbin.NewEnforcer(options.ModelPath, adapter)
+		m, err = newModel(options)
+		if err == nil {
+			err = checkStrategy(m, options.Strategy)
+		}
+		if err != nil {
+			logger.ErrorWithErr("加载Casbin模型失败", err, logger.String("modelPath", options.ModelPath))
+			initErr = err
+			return
+		}
+		dataSource := options.DataSource
+		if options.Driver == database.DriverMemory && dataSource == "" {
+			// 与业务库的默认内存库分开，避免两个连接池在同一个内存库上争用写锁
+			dataSource = defaultMemoryPolicyDB
+		}
+		db, openErr := database.Open(options.Driver, dataSource)
+		if openErr != nil {
+			logger.ErrorWithErr("连接Casbin策略数据库失败", openErr, logger.String("driver", options.Driver))
+			initErr = openErr
+			return
+		}
+		adapter, err = NewGormAdapter(db)
+		if err != nil {
+			logger.ErrorWithErr("初始化Casbin策略表失败", err)
+			initErr = err
+			return
+		}
+		if tenants != nil {
+			// 按域加载：创建执行器时不加载策略，只加载常驻的域和没有域字段的策略
+			enforcer, err = casbin.NewSyncedEnforcer(m)
+			if err == nil {
+				enforcer.SetAdapter(adapter)
+				err = enforcer.LoadFilteredPolicy(tenants.filter())
+			}
+		} else {
+			enforcer, err = casbin.NewSyncedEnforcer(m, adapter)
+		}
 	}
 
 	if err != nil {
@@ -66,21 +141,77 @@ func InitCasbin(options CasbinOptions) (initErr error) {
 		return
 	}
 	CasbinService = &CasbinEnforcer{
-		enforcer: enforcer,
-		adapter:  adapter,
-		model:    model,
+		options:      options,
+		adapter:      adapter,
+		domainEnable: options.EnableDomain,
+		abacEnable:   options.EnableABAC,
+		accessLog:    newAccessLog(defaultAccessLogSize),
+		tenants:      tenants,
 	}
-	logger.Info("CasbinService初始化成功")
+	CasbinService.addFunctions(enforcer)
+	CasbinService.active.Store(enforcer)
+	logger.Info("CasbinService初始化成功", logger.Bool("domain", options.EnableDomain), logger.Field("domains", options.Domains), logger.Bool("lazyDomains", options.LazyDomains))
 	return
 }
 
+// newModel 加载模型：优先使用ModelPath，未配置模型文件时使用内置模型
+// 补充数据范围的p3定义和字段级权限的r4/p4/m4定义，启用ABAC时补充r2/p2/m2定义
+func newModel(options CasbinOptions) (model.Model, error) {
+	m, err := loadModel(options)
+	if err != nil {
+		return nil, err
+	}
+	ensureDataScopeModel(m, options.EnableDomain)
+	ensureFieldModel(m, options.EnableDomain)
+	if options.EnableABAC {
+		ensureABACModel(m)
+	}
+	return m, nil
+}
+
+// loadModel 未配置模型文件时按是否启用多租户使用内置模型
+func loadModel(options CasbinOptions) (model.Model, error) {
+	if options.ModelPath == "" {
+		if options.EnableDomain {
+			return model.NewModelFromString(RBACWithDomainsModel)
+		}
+		return model.NewModelFromString(RBACModel)
+	}
+	modelPath, err := resolveModelPath(options.ModelPath)
+	if err != nil {
+		return nil, err
+	}
+	return model.NewModelFromFile(modelPath)
+}
+
+// resolveModelPath 相对路径按项目根目录解析
+func resolveModelPath(modelPath string) (string, error) {
+	if filepath.IsAbs(modelPath) {
+		return modelPath, nil
+	}
+	return path.GetAbsolutePath(modelPath)
+}
+
+// enforcer 当前执行器，模型热加载后返回新的执行器
+func (c *CasbinEnforcer) enforcer() *casbin.SyncedEnforcer {
+	return c.active.Load()
+}
+
+// mutate 在当前执行器上修改策略，ReloadModel替换执行器期间等待替换完成；fn中不能再调用mutate
+func (c *CasbinEnforcer) mutate(fn func(e *casbin.SyncedEnforcer) (bool, error)) (bool, error) {
+	c.swapMu.RLock()
+	defer c.swapMu.RUnlock()
+	return fn(c.enforcer())
+}
+
 func GetCasbinInstance() *CasbinEnforcer {
 	return CasbinService
 }
 
 // Enforce 权限判断
 func (c *CasbinEnforcer) Enforce(sub, obj, act string) (bool, error) {
-	ok, err := c.enforcer.Enforce(sub, obj, act)
+	c.checkGrants([]string{sub}, time.Now())
+	ok, _, err := c.enforceCached(sub, obj, act)
 	if err != nil {
 		logger.ErrorWithErr("Casbin权限校验失败", err, logger.String("sub", sub), logger.String("obj", obj), logger.String("act", act))
 	}
@@ -89,52 +220,87 @@ func (c *CasbinEnforcer) Enforce(sub, obj, act string) (bool, error) {
 
 // AddPolicy 添加策略
 func (c *CasbinEnforcer) AddPolicy(params ...interface{}) (bool, error) {
-	ok, err := c.enforcer.AddPolicy(params...)
+	c.ensureRuleDomains("p", "p", paramsToRule(params))
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.AddPolicy(params...)
+	})
 	if err != nil {
 		logger.ErrorWithErr("添加Casbin策略失败", err, logger.Field("params", params))
 	}
+	if ok {
+		c.invalidateRules("p", paramsToRule(params))
+		err = c.persist()
+	}
 	return ok, err
 }
 
 // RemovePolicy 删除策略
 func (c *CasbinEnforcer) RemovePolicy(params ...interface{}) (bool, error) {
-	ok, err := c.enforcer.RemovePolicy(params...)
+	c.ensureRuleDomains("p", "p", paramsToRule(params))
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.RemovePolicy(params...)
+	})
 	if err != nil {
 		logger.ErrorWithErr("删除Casbin策略失败", err, logger.Field("params", params))
 	}
+	if ok {
+		c.invalidateRules("p", paramsToRule(params))
+		err = c.persist()
+	}
 	return ok, err
 }
 
 // RemoveFilteredPolicy 按条件删除策略
 func (c *CasbinEnforcer) RemoveFilteredPolicy(fieldIndex int, fieldValues ...string) (bool, error) {
-	ok, err := c.enforcer.RemoveFilteredPolicy(fieldIndex, fieldValues...)
+	c.ensureFilterDomain("p", "p", fieldIndex, fieldValues)
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.RemoveFilteredPolicy(fieldIndex, fieldValues...)
+	})
 	if err != nil {
 		logger.ErrorWithErr("按条件删除Casbin策略失败", err, logger.Int("fieldIndex", fieldIndex), logger.Field("fieldValues", fieldValues))
 	}
+	if ok {
+		c.invalidateAll()
+		err = c.persist()
+	}
 	return ok, err
 }
 
 // AddPolicies 批量添加策略
 func (c *CasbinEnforcer) AddPolicies(rules [][]string) (bool, error) {
-	ok, err := c.enforcer.AddPolicies(rules)
+	c.ensureRuleDomains("p", "p", rules...)
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.AddPolicies(rules)
+	})
 	if err != nil {
 		logger.ErrorWithErr("批量添加Casbin策略失败", err, logger.Field("rules", rules))
 	}
+	if ok {
+		c.invalidateRules("p", rules...)
+		err = c.persist()
+	}
 	return ok, err
 }
 
 // RemovePolicies 批量删除策略
 func (c *CasbinEnforcer) RemovePolicies(rules [][]string) (bool, error) {
-	ok, err := c.enforcer.RemovePolicies(rules)
+	c.ensureRuleDomains("p", "p", rules...)
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.RemovePolicies(rules)
+	})
 	if err != nil {
 		logger.ErrorWithErr("批量删除Casbin策略失败", err, logger.Field("rules", rules))
 	}
+	if ok {
+		c.invalidateRules("p", rules...)
+		err = c.persist()
+	}
 	return ok, err
 }
 
 // GetPolicy 获取所有策略
 func (c *CasbinEnforcer) GetPolicy() [][]string {
-	policies, err := c.enforcer.GetPolicy()
+	policies, err := c.enforcer().GetPolicy()
 	if err != nil {
 		logger.ErrorWithErr("获取策略失败", err)
 		return nil
@@ -144,25 +310,40 @@ func (c *CasbinEnforcer) GetPolicy() [][]string {
 
 // SavePolicy 持久化策略
 func (c *CasbinEnforcer) SavePolicy() error {
-	err := c.enforcer.SavePolicy()
+	err := c.enforcer().SavePolicy()
 	if err != nil {
 		logger.ErrorWithErr("保存Casbin策略失败", err)
 	}
 	return err
 }
 
-// LoadPolicy 重新加载策略
+// persist 文件存储不支持增量写入(AddPolicy等只修改内存)，修改后整体写回策略文件，避免重启后丢失
+// 写回时会通知watcher，其他实例重新加载策略文件；数据库存储已在修改时逐条写入
+func (c *CasbinEnforcer) persist() error {
+	if c.adapter != nil {
+		return nil
+	}
+	return c.SavePolicy()
+}
+
+// LoadPolicy 重新加载策略，按域加载时只重新加载已加载的域
 func (c *CasbinEnforcer) LoadPolicy() error {
-	err := c.enforcer.LoadPolicy()
+	_, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		if c.tenants != nil {
+			return true, e.LoadFilteredPolicy(c.tenants.filter())
+		}
+		return true, e.LoadPolicy()
+	})
 	if err != nil {
 		logger.ErrorWithErr("加载Casbin策略失败", err)
 	}
+	c.invalidateAll()
 	return err
 }
 
 // GetRolesForUser 获取用户的所有角色
 func (c *CasbinEnforcer) GetRolesForUser(user string) []string {
-	roles, err := c.enforcer.GetRolesForUser(user)
+	roles, err := c.enforcer().GetRolesForUser(user)
 	if err != nil {
 		logger.ErrorWithErr("获取用户角色失败", err, logger.String("user", user))
 		return nil
@@ -172,7 +353,7 @@ func (c *CasbinEnforcer) GetRolesForUser(user string) []string {
 
 // GetUsersForRole 获取角色下所有用户
 func (c *CasbinEnforcer) GetUsersForRole(role string) []string {
-	users, err := c.enforcer.GetUsersForRole(role)
+	users, err := c.enforcer().GetUsersForRole(role)
 	if err != nil {
 		logger.ErrorWithErr("获取角色用户失败", err, logger.String("role", role))
 		return nil
@@ -182,25 +363,37 @@ func (c *CasbinEnforcer) GetUsersForRole(role string) []string {
 
 // AddRoleForUser 给用户添加角色
 func (c *CasbinEnforcer) AddRoleForUser(user, role string) (bool, error) {
-	ok, err := c.enforcer.AddRoleForUser(user, role)
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.AddRoleForUser(user, role)
+	})
 	if err != nil {
 		logger.ErrorWithErr("添加用户角色失败", err, logger.String("user", user), logger.String("role", role))
 	}
+	if ok {
+		c.invalidateRules("g", []string{user, role})
+		err = c.persist()
+	}
 	return ok, err
 }
 
 // DeleteRoleForUser 移除用户的角色
 func (c *CasbinEnforcer) DeleteRoleForUser(user, role string) (bool, error) {
-	ok, err := c.enforcer.DeleteRoleForUser(user, role)
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.DeleteRoleForUser(user, role)
+	})
 	if err != nil {
 		logger.ErrorWithErr("移除用户角色失败", err, logger.String("user", user), logger.String("role", role))
 	}
+	if ok {
+		c.invalidateRules("g", []string{user, role})
+		err = c.persist()
+	}
 	return ok, err
 }
 
 // GetAllSubjects 获取所有subject
 func (c *CasbinEnforcer) GetAllSubjects() []string {
-	subjects, err := c.enforcer.GetAllSubjects()
+	subjects, err := c.enforcer().GetAllSubjects()
 	if err != nil {
 		logger.ErrorWithErr("获取subject失败", err)
 		return nil
@@ -210,7 +403,7 @@ func (c *CasbinEnforcer) GetAllSubjects() []string {
 
 // GetAllObjects 获取所有object
 func (c *CasbinEnforcer) GetAllObjects() []string {
-	objects, err := c.enforcer.GetAllObjects()
+	objects, err := c.enforcer().GetAllObjects()
 	if err != nil {
 		logger.ErrorWithErr("获取object失败", err)
 		return nil
@@ -220,7 +413,7 @@ func (c *CasbinEnforcer) GetAllObjects() []string {
 
 // GetAllActions 获取所有action
 func (c *CasbinEnforcer) GetAllActions() []string {
-	actions, err := c.enforcer.GetAllActions()
+	actions, err := c.enforcer().GetAllActions()
 	if err != nil {
 		logger.ErrorWithErr("获取action失败", err)
 		return nil
@@ -230,10 +423,55 @@ func (c *CasbinEnforcer) GetAllActions() []string {
 
 // GetAllRoles 获取所有角色
 func (c *CasbinEnforcer) GetAllRoles() []string {
-	roles, err := c.enforcer.GetAllRoles()
+	roles, err := c.enforcer().GetAllRoles()
 	if err != nil {
 		logger.ErrorWithErr("获取角色失败", err)
 		return nil
 	}
 	return roles
 }
+
+// GetFilteredPolicy 按字段过滤策略，空字符串表示该字段不过滤
+func (c *CasbinEnforcer) GetFilteredPolicy(fieldIndex int, fieldValues ...string) [][]string {
+	c.ensureFilterDomain("p", "p", fieldIndex, fieldValues)
+	policies, err := c.enforcer().GetFilteredPolicy(fieldIndex, fieldValues...)
+	if err != nil {
+		logger.ErrorWithErr("按条件获取策略失败", err, logger.Int("fieldIndex", fieldIndex), logger.Field("fieldValues", fieldValues))
+		return nil
+	}
+	return policies
+}
+
+// GetGroupingPolicy 获取所有角色继承(g)策略
+func (c *CasbinEnforcer) GetGroupingPolicy() [][]string {
+	policies, err := c.enforcer().GetGroupingPolicy()
+	if err != nil {
+		logger.ErrorWithErr("获取角色继承策略失败", err)
+		return nil
+	}
+	return policies
+}
+
+// HasPolicy 判断策略是否存在
+func (c *CasbinEnforcer) HasPolicy(params ...interface{}) bool {
+	c.ensureRuleDomains("p", "p", paramsToRule(params))
+	ok, err := c.enforcer().HasPolicy(params...)
+	if err != nil {
+		logger.ErrorWithErr("查询Casbin策略失败", err, logger.Field("params", params))
+	}
+	return ok
+}
+
+// paramsToRule 将AddPolicy/RemovePolicy的参数转换为规则
+func paramsToRule(params []interface{}) []string {
+	if len(params) == 1 {
+		if rule, ok := params[0].([]string); ok {
+			return rule
+		}
+	}
+	rule := make([]string, 0, len(params))
+	for _, param := range params {
+		rule = append(rule, fmt.Sprint(param))
+	}
+	return rule
+}
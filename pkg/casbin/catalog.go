@@ -0,0 +1,151 @@
+package casbin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+// Permission 权限目录中的一项，对应一个路由
+type Permission struct {
+	Name        string `json:"name"`        // 权限名，由处理函数推导，如policy.ListPolicies
+	Method      string `json:"method"`      // HTTP方法
+	Path        string `json:"path"`        // 路由路径(含:param)，即策略中的obj
+	Group       string `json:"group"`       // 分组，取/api/v1之后的第一段
+	Description string `json:"description"` // 说明
+}
+
+// CatalogIssue 策略校验发现的问题
+type CatalogIssue struct {
+	Ptype  string   `json:"ptype"`
+	Rule   []string `json:"rule"`
+	Reason string   `json:"reason"`
+}
+
+// Catalog 权限目录，启动时由路由表生成
+type Catalog struct {
+	mu          sync.RWMutex
+	permissions []Permission
+}
+
+var defaultCatalog = &Catalog{}
+
+// GetCatalog 获取全局权限目录
+func GetCatalog() *Catalog {
+	return defaultCatalog
+}
+
+// Set 替换目录中的全部权限，按路径和方法排序
+func (c *Catalog) Set(permissions []Permission) {
+	sorted := append([]Permission(nil), permissions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+	c.mu.Lock()
+	c.permissions = sorted
+	c.mu.Unlock()
+}
+
+// List 按分组列出权限，group为空时返回全部
+func (c *Catalog) List(group string) []Permission {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	permissions := make([]Permission, 0, len(c.permissions))
+	for _, p := range c.permissions {
+		if group == "" || p.Group == group {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
+// Groups 全部分组
+func (c *Catalog) Groups() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	seen := make(map[string]bool)
+	groups := make([]string, 0)
+	for _, p := range c.permissions {
+		if !seen[p.Group] {
+			seen[p.Group] = true
+			groups = append(groups, p.Group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// Match 找出与策略中的obj匹配的路由：策略可以是路由本身、keyMatch2模式(/users/*)、regex:正则或具体路径(/users/1)
+func (c *Catalog) Match(obj string) []Permission {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var matched []Permission
+	for _, p := range c.permissions {
+		if p.Path == obj || PathMatch(p.Path, obj) || util.KeyMatch2(obj, p.Path) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// isPathObject obj是否为路由：以/开头或为regex:正则
+func isPathObject(obj string) bool {
+	return strings.HasPrefix(obj, "/") || strings.HasPrefix(obj, RegexPrefix)
+}
+
+// ValidateAgainstCatalog 校验p策略的obj和act是否指向存在的路由，obj不是路由的策略(如菜单、按钮权限)不校验；
+// 按域加载时校验存储中的全部策略，而不只是已加载的域
+func (c *CasbinEnforcer) ValidateAgainstCatalog(catalog *Catalog) ([]CatalogIssue, error) {
+	m, err := c.storedModel()
+	if err != nil {
+		return nil, err
+	}
+	assertion, ok := m["p"]["p"]
+	if !ok {
+		return nil, nil
+	}
+	objIndex, actIndex := -1, -1
+	for i, token := range assertion.Tokens {
+		switch token {
+		case "p_obj":
+			objIndex = i
+		case "p_act":
+			actIndex = i
+		}
+	}
+	if objIndex < 0 {
+		return nil, nil
+	}
+
+	issues := make([]CatalogIssue, 0)
+	for _, rule := range assertion.Policy {
+		if objIndex >= len(rule) || !isPathObject(rule[objIndex]) {
+			continue
+		}
+		matched := catalog.Match(rule[objIndex])
+		if len(matched) == 0 {
+			issues = append(issues, CatalogIssue{Ptype: "p", Rule: rule, Reason: fmt.Sprintf("路由 %s 不存在", rule[objIndex])})
+			continue
+		}
+		if actIndex < 0 || actIndex >= len(rule) || rule[actIndex] == "*" {
+			continue
+		}
+		methodFound := false
+		for _, p := range matched {
+			if strings.EqualFold(p.Method, rule[actIndex]) {
+				methodFound = true
+				break
+			}
+		}
+		if !methodFound {
+			issues = append(issues, CatalogIssue{Ptype: "p", Rule: rule, Reason: fmt.Sprintf("路由 %s 不支持方法 %s", rule[objIndex], rule[actIndex])})
+		}
+	}
+	return issues, nil
+}
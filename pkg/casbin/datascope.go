@@ -0,0 +1,329 @@
+package casbin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_casbin/internal/logger"
+	"sync"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 数据范围策略保存在模型的p3定义中，与角色策略共用存储、watcher和导入导出
+// 策略格式：p3, 主体(用户或角色), 资源类型(或*), 数据范围
+// 例如：p3, admin, *, all    p3, manager, account, department
+// 启用多租户时在主体之后多一个域字段，角色在各域中的数据范围相互独立，例如：p3, manager, t1, account, department
+const (
+	DataScopePtype                  = "p3"
+	DataScopePolicyDefinition       = "sub, obj, scope"
+	DataScopeDomainPolicyDefinition = "sub, dom, obj, scope"
+)
+
+// DataScope 行级数据范围
+type DataScope string
+
+const (
+	DataScopeAll        DataScope = "all"        // 全部数据
+	DataScopeTenant     DataScope = "tenant"     // 本租户
+	DataScopeDepartment DataScope = "department" // 本部门
+	DataScopeSelf       DataScope = "self"       // 仅本人
+)
+
+// dataScopeOrder 由宽到窄，没有配置任何数据范围时按self处理
+var dataScopeOrder = []DataScope{DataScopeAll, DataScopeTenant, DataScopeDepartment, DataScopeSelf}
+
+// ParseDataScope 解析数据范围
+func ParseDataScope(s string) (DataScope, error) {
+	for _, scope := range dataScopeOrder {
+		if string(scope) == s {
+			return scope, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的数据范围: %s", s)
+}
+
+func dataScopeRank(scope DataScope) int {
+	for i, s := range dataScopeOrder {
+		if s == scope {
+			return len(dataScopeOrder) - i
+		}
+	}
+	return 0
+}
+
+// DataScopeColumns 资源表中用于数据范围过滤的列，为空表示该资源不支持对应的范围
+type DataScopeColumns struct {
+	Tenant     string // 租户列
+	Department string // 部门列
+	Owner      string // 所有者列
+}
+
+var (
+	dataScopeMu      sync.RWMutex
+	dataScopeColumns = make(map[string]DataScopeColumns)
+)
+
+// RegisterDataScopeColumns 注册资源类型的数据范围列，重复注册时覆盖
+func RegisterDataScopeColumns(resource string, columns DataScopeColumns) {
+	dataScopeMu.Lock()
+	defer dataScopeMu.Unlock()
+	dataScopeColumns[resource] = columns
+}
+
+func dataScopeColumnsOf(resource string) DataScopeColumns {
+	dataScopeMu.RLock()
+	defer dataScopeMu.RUnlock()
+	return dataScopeColumns[resource]
+}
+
+// Principal 发起请求的主体，由鉴权中间件放入请求上下文
+type Principal struct {
+	UserID       string   // 用户ID
+	TenantID     string   // 租户ID
+	DepartmentID string   // 部门ID
+	Domain       string   // 域，启用多租户时有效
+	Subjects     []string // 参与鉴权的主体：用户ID和token中的角色
+}
+
+type (
+	principalKey struct{}
+	systemKey    struct{}
+)
+
+// WithPrincipal 将主体放入上下文，p为nil时清除上下文中的主体，之后的查询按无权限处理
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(context.WithValue(ctx, systemKey{}, false), principalKey{}, p)
+}
+
+// WithSystemPrincipal 标记为系统内部调用(定时任务、命令行、关系校验等)，清除上下文中的主体，之后的查询不按数据范围过滤
+// 没有主体的上下文默认拒绝全部数据，内部调用需要显式声明
+func WithSystemPrincipal(ctx context.Context) context.Context {
+	return context.WithValue(context.WithValue(ctx, principalKey{}, nil), systemKey{}, true)
+}
+
+func isSystem(ctx context.Context) bool {
+	system, _ := ctx.Value(systemKey{}).(bool)
+	return system
+}
+
+// PrincipalFrom 从上下文取出主体
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// ensureDataScopeModel 模型中没有p3定义时补充数据范围定义，启用多租户时带域字段
+func ensureDataScopeModel(m model.Model, domainEnable bool) {
+	if _, ok := m["p"][DataScopePtype]; ok {
+		return
+	}
+	if domainEnable {
+		m.AddDef("p", DataScopePtype, DataScopeDomainPolicyDefinition)
+		return
+	}
+	m.AddDef("p", DataScopePtype, DataScopePolicyDefinition)
+}
+
+// dataScopeKey p3规则中数据范围之前的字段：(sub, resource)，启用多租户时为(sub, dom, resource)
+func (c *CasbinEnforcer) dataScopeKey(sub, domain, resource string) ([]string, error) {
+	if c.domainEnable {
+		if domain == "" {
+			return nil, errors.New("已启用多租户，domain不能为空")
+		}
+		return []string{sub, domain, resource}, nil
+	}
+	return []string{sub, resource}, nil
+}
+
+// SetDataScope 设置主体在域中对资源类型的数据范围，已存在时覆盖；domain仅在启用多租户时使用
+func (c *CasbinEnforcer) SetDataScope(sub, domain, resource string, scope DataScope) (bool, error) {
+	key, err := c.dataScopeKey(sub, domain, resource)
+	if err != nil {
+		return false, err
+	}
+	if c.domainEnable {
+		c.ensureDomain(domain)
+	}
+	if _, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.RemoveFilteredNamedPolicy(DataScopePtype, 0, key...)
+	}); err != nil {
+		logger.ErrorWithErr("删除数据范围策略失败", err, logger.Field("rule", key))
+		return false, err
+	}
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.AddNamedPolicy(DataScopePtype, append(key, string(scope)))
+	})
+	if err != nil {
+		logger.ErrorWithErr("添加数据范围策略失败", err, logger.Field("rule", key))
+	}
+	if ok {
+		err = c.persist()
+	}
+	return ok, err
+}
+
+// RemoveDataScope 删除主体在域中对资源类型的数据范围
+func (c *CasbinEnforcer) RemoveDataScope(sub, domain, resource string) (bool, error) {
+	key, err := c.dataScopeKey(sub, domain, resource)
+	if err != nil {
+		return false, err
+	}
+	if c.domainEnable {
+		c.ensureDomain(domain)
+	}
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.RemoveFilteredNamedPolicy(DataScopePtype, 0, key...)
+	})
+	if err != nil {
+		logger.ErrorWithErr("删除数据范围策略失败", err, logger.Field("rule", key))
+	}
+	if ok {
+		err = c.persist()
+	}
+	return ok, err
+}
+
+// GetDataScopes 获取全部数据范围策略
+func (c *CasbinEnforcer) GetDataScopes() [][]string {
+	rules, err := c.enforcer().GetNamedPolicy(DataScopePtype)
+	if err != nil {
+		logger.ErrorWithErr("获取数据范围策略失败", err)
+	}
+	return rules
+}
+
+// ResolveDataScope 主体及其继承的全部角色在资源类型上最宽的数据范围，启用多租户时只使用主体所在域的策略
+func (c *CasbinEnforcer) ResolveDataScope(p *Principal, resource string) DataScope {
+	var domain []string
+	if c.domainEnable {
+		if p.Domain == "" {
+			return DataScopeSelf
+		}
+		domain = []string{p.Domain}
+		c.ensureDomain(p.Domain)
+	}
+	subjects := make(map[string]struct{})
+	for _, sub := range p.Subjects {
+		if sub == "" {
+			continue
+		}
+		subjects[sub] = struct{}{}
+		roles, err := c.enforcer().GetImplicitRolesForUser(sub, domain...)
+		if err != nil {
+			logger.ErrorWithErr("获取继承角色失败", err, logger.String("sub", sub))
+			continue
+		}
+		for _, role := range roles {
+			subjects[role] = struct{}{}
+		}
+	}
+
+	result := DataScopeSelf
+	for sub := range subjects {
+		rules, err := c.enforcer().GetFilteredNamedPolicy(DataScopePtype, 0, append([]string{sub}, domain...)...)
+		if err != nil {
+			continue
+		}
+		for _, rule := range rules {
+			// 主体和域之后为(resource, scope)
+			offset := 1 + len(domain)
+			if len(rule) < offset+2 || rule[offset] != resource && rule[offset] != "*" {
+				continue
+			}
+			if scope := DataScope(rule[offset+1]); dataScopeRank(scope) > dataScopeRank(result) {
+				result = scope
+			}
+		}
+	}
+	return result
+}
+
+// dataScopeFilter 数据范围最终落到的过滤条件
+type dataScopeFilter struct {
+	unrestricted bool      // 不过滤
+	scope        DataScope // 生效的范围，为空表示拒绝全部
+	column       string
+	value        string
+}
+
+// filterOf 从上下文中的主体计算过滤条件；没有主体时只有WithSystemPrincipal标记的内部调用不过滤，其余拒绝全部
+// 资源不支持某个范围或主体缺少对应属性时逐级收窄，直到拒绝全部
+func filterOf(ctx context.Context, resource string) dataScopeFilter {
+	p, ok := PrincipalFrom(ctx)
+	if !ok {
+		if isSystem(ctx) {
+			return dataScopeFilter{unrestricted: true}
+		}
+		return dataScopeFilter{}
+	}
+	if CasbinService == nil {
+		return dataScopeFilter{}
+	}
+	resolved := CasbinService.ResolveDataScope(p, resource)
+	columns := dataScopeColumnsOf(resource)
+	for _, scope := range dataScopeOrder {
+		if dataScopeRank(scope) > dataScopeRank(resolved) {
+			continue
+		}
+		var column, value string
+		switch scope {
+		case DataScopeAll:
+			return dataScopeFilter{unrestricted: true, scope: scope}
+		case DataScopeTenant:
+			column, value = columns.Tenant, p.TenantID
+		case DataScopeDepartment:
+			column, value = columns.Department, p.DepartmentID
+		case DataScopeSelf:
+			column, value = columns.Owner, p.UserID
+		}
+		if column != "" && value != "" {
+			return dataScopeFilter{scope: scope, column: column, value: value}
+		}
+	}
+	return dataScopeFilter{}
+}
+
+// DataScopeQuery 按请求上下文中的主体生成GORM数据范围条件，用法：db.Scopes(casbin.DataScopeQuery(ctx, "account"))
+func DataScopeQuery(ctx context.Context, resource string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		filter := filterOf(ctx, resource)
+		if filter.unrestricted {
+			return db
+		}
+		if filter.scope == "" {
+			return db.Where("1 = 0")
+		}
+		return db.Where(clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: filter.column},
+			Value:  filter.value,
+		})
+	}
+}
+
+// DataScopeRow 非数据库资源(如内存中的工作流实例)的数据范围属性
+type DataScopeRow struct {
+	Tenant     string
+	Department string
+	Owner      string
+}
+
+// DataScopeAllows 判断当前主体能否看到该行，规则与DataScopeQuery一致
+func DataScopeAllows(ctx context.Context, resource string, row DataScopeRow) bool {
+	filter := filterOf(ctx, resource)
+	if filter.unrestricted {
+		return true
+	}
+	switch filter.scope {
+	case DataScopeTenant:
+		return row.Tenant == filter.value
+	case DataScopeDepartment:
+		return row.Department == filter.value
+	case DataScopeSelf:
+		return row.Owner == filter.value
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package casbin
+
+import (
+	"errors"
+	"fmt"
+	"go_casbin/internal/logger"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// Strategy 多主体鉴权结果合并策略
+type Strategy string
+
+const (
+	// StrategyAnyAllow 任一主体允许即放行
+	StrategyAnyAllow Strategy = "any-allow"
+	// StrategyDenyOverrides 任一主体命中显式deny即拒绝，否则任一允许即放行
+	StrategyDenyOverrides Strategy = "deny-overrides"
+)
+
+// ParseStrategy 解析配置中的合并策略，未知值回退为any-allow
+func ParseStrategy(s string) Strategy {
+	if Strategy(s) == StrategyDenyOverrides {
+		return StrategyDenyOverrides
+	}
+	return StrategyAnyAllow
+}
+
+// Decision 鉴权决策结果
+type Decision struct {
+	Allowed  bool     `json:"allowed"`  // 是否放行
+	Subject  string   `json:"subject"`  // 决定结果的主体
+	Policy   []string `json:"policy"`   // 命中的策略
+	Strategy Strategy `json:"strategy"` // 合并策略
+	Reason   string   `json:"reason"`   // 决策原因
+}
+
+// Strategy 初始化时配置的多主体合并策略
+func (c *CasbinEnforcer) Strategy() Strategy {
+	return c.options.Strategy
+}
+
+// EnforceEx 权限判断并返回命中的策略
+func (c *CasbinEnforcer) EnforceEx(rvals ...interface{}) (bool, []string, error) {
+	ok, explain, err := c.enforceCached(rvals...)
+	if err != nil {
+		logger.ErrorWithErr("Casbin权限校验失败", err, logger.Field("rvals", rvals))
+	}
+	return ok, explain, err
+}
+
+// enforceCached 启用缓存时优先读取缓存，未命中再执行matcher
+func (c *CasbinEnforcer) enforceCached(rvals ...interface{}) (bool, []string, error) {
+	c.ensureRequestDomain(rvals)
+	if c.cache == nil {
+		return c.enforcer().EnforceEx(rvals...)
+	}
+	key, cacheable := cacheKey(rvals)
+	if !cacheable {
+		return c.enforcer().EnforceEx(rvals...)
+	}
+	// 先读取版本再执行matcher，期间策略变更导致的失效会使本次结果不写入缓存
+	version := c.cache.version()
+	if value, ok := c.cache.get(key, version); ok {
+		return value.Allowed, value.Policy, nil
+	}
+	ok, explain, err := c.enforcer().EnforceEx(rvals...)
+	if err == nil {
+		c.cache.set(key, cachedDecision{Allowed: ok, Policy: explain}, version)
+	}
+	return ok, explain, err
+}
+
+// EnforceSubjects 对多个主体(用户本身及其全部角色)分别鉴权并按策略合并结果
+// rvals 为主体之后的请求参数，例如 (obj, act) 或 (dom, obj, act)
+func (c *CasbinEnforcer) EnforceSubjects(strategy Strategy, subjects []string, rvals ...interface{}) (*Decision, error) {
+	// 到期的限时授权在本次鉴权前隐藏，数据库状态变更在后台处理
+	c.checkGrants(subjects, time.Now())
+	return mergeSubjects(strategy, subjects, func(sub string) (bool, []string, error) {
+		request := append([]interface{}{sub}, rvals...)
+		c.recordAccess(request)
+		return c.EnforceEx(request...)
+	})
+}
+
+// mergeSubjects 依次对每个主体执行enforce并按strategy合并结果
+// deny-overrides依赖未放行时EnforceEx返回命中的deny策略，需要模型包含p.eft，由checkStrategy在初始化时校验
+func mergeSubjects(strategy Strategy, subjects []string, enforce func(sub string) (bool, []string, error)) (*Decision, error) {
+	decision := &Decision{Strategy: strategy}
+	var allowed *Decision
+	for _, sub := range uniqueSubjects(subjects) {
+		ok, explain, err := enforce(sub)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if allowed == nil {
+				allowed = &Decision{Allowed: true, Subject: sub, Policy: explain, Strategy: strategy}
+			}
+			if strategy == StrategyAnyAllow {
+				break
+			}
+			continue
+		}
+		// 未放行但命中了策略，说明命中的是显式deny
+		if strategy == StrategyDenyOverrides && len(explain) > 0 {
+			decision.Subject = sub
+			decision.Policy = explain
+			decision.Reason = fmt.Sprintf("主体 %s 命中拒绝策略 [%s]", sub, strings.Join(explain, ", "))
+			return decision, nil
+		}
+	}
+	if allowed != nil {
+		allowed.Reason = fmt.Sprintf("主体 %s 命中允许策略 [%s]", allowed.Subject, strings.Join(allowed.Policy, ", "))
+		return allowed, nil
+	}
+	decision.Reason = "没有主体命中允许策略"
+	return decision, nil
+}
+
+// checkStrategy deny-overrides需要模型的p定义包含eft字段，否则无法区分显式deny与未命中，会退化为any-allow
+func checkStrategy(m model.Model, strategy Strategy) error {
+	if strategy != StrategyDenyOverrides {
+		return nil
+	}
+	if assertion, ok := m["p"]["p"]; ok {
+		for _, token := range assertion.Tokens {
+			if token == "p_eft" {
+				return nil
+			}
+		}
+	}
+	return errors.New("合并策略deny-overrides需要模型的p定义包含eft字段(如 p = sub, obj, act, eft)，内置模型不支持")
+}
@@ -0,0 +1,136 @@
+package casbin
+
+import (
+	"go_casbin/internal/logger"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// RBACWithDomainsModel 内置的多租户RBAC模型，请求格式为 (sub, dom, obj, act)
+// 角色继承关系按域隔离：g = 用户, 角色, 域；obj与act的匹配规则同RBACModel
+const RBACWithDomainsModel = `
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, dom, obj, act
+
+[role_definition]
+g = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && pathMatch(r.obj, p.obj) && (r.act == p.act || p.act == "*")
+`
+
+// IsDomainEnabled 是否启用多租户模型
+func (c *CasbinEnforcer) IsDomainEnabled() bool {
+	return c.domainEnable
+}
+
+// EnforceWithDomain 多租户权限判断
+func (c *CasbinEnforcer) EnforceWithDomain(sub, dom, obj, act string) (bool, error) {
+	ok, _, err := c.enforceCached(sub, dom, obj, act)
+	if err != nil {
+		logger.ErrorWithErr("Casbin多租户权限校验失败", err, logger.String("sub", sub), logger.String("dom", dom), logger.String("obj", obj), logger.String("act", act))
+	}
+	return ok, err
+}
+
+// AddPolicyInDomain 在指定域内添加策略
+func (c *CasbinEnforcer) AddPolicyInDomain(sub, dom, obj, act string) (bool, error) {
+	return c.AddPolicy(sub, dom, obj, act)
+}
+
+// RemovePolicyInDomain 在指定域内删除策略
+func (c *CasbinEnforcer) RemovePolicyInDomain(sub, dom, obj, act string) (bool, error) {
+	return c.RemovePolicy(sub, dom, obj, act)
+}
+
+// AddRoleForUserInDomain 在指定域内给用户添加角色
+func (c *CasbinEnforcer) AddRoleForUserInDomain(user, role, domain string) (bool, error) {
+	c.ensureDomain(domain)
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.AddRoleForUserInDomain(user, role, domain)
+	})
+	if err != nil {
+		logger.ErrorWithErr("添加域内用户角色失败", err, logger.String("user", user), logger.String("role", role), logger.String("domain", domain))
+	}
+	if ok {
+		c.invalidateRules("g", []string{user, role, domain})
+		err = c.persist()
+	}
+	return ok, err
+}
+
+// DeleteRoleForUserInDomain 在指定域内移除用户的角色
+func (c *CasbinEnforcer) DeleteRoleForUserInDomain(user, role, domain string) (bool, error) {
+	c.ensureDomain(domain)
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.DeleteRoleForUserInDomain(user, role, domain)
+	})
+	if err != nil {
+		logger.ErrorWithErr("移除域内用户角色失败", err, logger.String("user", user), logger.String("role", role), logger.String("domain", domain))
+	}
+	if ok {
+		c.invalidateRules("g", []string{user, role, domain})
+		err = c.persist()
+	}
+	return ok, err
+}
+
+// GetRolesForUserInDomain 获取用户在指定域内的角色
+func (c *CasbinEnforcer) GetRolesForUserInDomain(user, domain string) []string {
+	c.ensureDomain(domain)
+	return c.enforcer().GetRolesForUserInDomain(user, domain)
+}
+
+// GetUsersForRoleInDomain 获取指定域内拥有该角色的用户
+func (c *CasbinEnforcer) GetUsersForRoleInDomain(role, domain string) []string {
+	c.ensureDomain(domain)
+	return c.enforcer().GetUsersForRoleInDomain(role, domain)
+}
+
+// GetPermissionsForUserInDomain 获取用户在指定域内的直接权限
+func (c *CasbinEnforcer) GetPermissionsForUserInDomain(user, domain string) [][]string {
+	c.ensureDomain(domain)
+	return c.enforcer().GetPermissionsForUserInDomain(user, domain)
+}
+
+// GetAllDomains 获取所有域
+func (c *CasbinEnforcer) GetAllDomains() []string {
+	domains, err := c.enforcer().GetAllDomains()
+	if err != nil {
+		logger.ErrorWithErr("获取域失败", err)
+		return nil
+	}
+	return domains
+}
+
+// DeleteDomains 删除域及其下的全部策略和角色关系
+func (c *CasbinEnforcer) DeleteDomains(domains ...string) (bool, error) {
+	ok, err := c.mutate(func(e *casbin.SyncedEnforcer) (bool, error) {
+		return e.DeleteDomains(domains...)
+	})
+	if err != nil {
+		logger.ErrorWithErr("删除域失败", err, logger.Field("domains", domains))
+	}
+	if ok {
+		c.invalidateAll()
+		err = c.persist()
+	}
+	return ok, err
+}
+
+// GetAllRolesByDomain 获取指定域内的所有角色
+func (c *CasbinEnforcer) GetAllRolesByDomain(domain string) []string {
+	c.ensureDomain(domain)
+	roles, err := c.enforcer().GetAllRolesByDomain(domain)
+	if err != nil {
+		logger.ErrorWithErr("获取域内角色失败", err, logger.String("domain", domain))
+		return nil
+	}
+	return roles
+}
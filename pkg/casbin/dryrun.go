@@ -0,0 +1,182 @@
+package casbin
+
+import (
+	"container/list"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// defaultAccessLogSize 记录的最近鉴权请求数
+const defaultAccessLogSize = 1000
+
+// PolicyChange 待验证的策略变更
+type PolicyChange struct {
+	Add    []PolicyLine `json:"add"`    // 新增的规则
+	Remove []PolicyLine `json:"remove"` // 删除的规则
+}
+
+// DecisionFlip 变更前后结果不同的请求
+type DecisionFlip struct {
+	Request []string `json:"request"` // 请求参数
+	Before  bool     `json:"before"`  // 变更前是否放行
+	After   bool     `json:"after"`   // 变更后是否放行
+}
+
+// DryRunResult 策略变更预演结果
+type DryRunResult struct {
+	Total   int            `json:"total"`   // 验证的请求数
+	Granted []DecisionFlip `json:"granted"` // 由拒绝变为放行
+	Revoked []DecisionFlip `json:"revoked"` // 由放行变为拒绝
+}
+
+// accessLog 最近鉴权请求的去重记录，超出容量时淘汰最久未出现的请求
+type accessLog struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+func newAccessLog(capacity int) *accessLog {
+	return &accessLog{capacity: capacity, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *accessLog) record(request []string) {
+	key := strings.Join(request, "\x1f")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.ll.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.ll.PushFront(request)
+	for l.ll.Len() > l.capacity {
+		el := l.ll.Back()
+		l.ll.Remove(el)
+		delete(l.items, strings.Join(el.Value.([]string), "\x1f"))
+	}
+}
+
+func (l *accessLog) list() [][]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	requests := make([][]string, 0, l.ll.Len())
+	for el := l.ll.Front(); el != nil; el = el.Next() {
+		requests = append(requests, el.Value.([]string))
+	}
+	return requests
+}
+
+// recordAccess 记录一次鉴权请求，供预演策略变更时使用
+func (c *CasbinEnforcer) recordAccess(rvals []interface{}) {
+	if c.accessLog == nil {
+		return
+	}
+	request := make([]string, 0, len(rvals))
+	for _, v := range rvals {
+		s, ok := v.(string)
+		if !ok {
+			return
+		}
+		request = append(request, s)
+	}
+	c.accessLog.record(request)
+}
+
+// RecentRequests 最近的鉴权请求，按最近出现排序
+func (c *CasbinEnforcer) RecentRequests() [][]string {
+	if c.accessLog == nil {
+		return [][]string{}
+	}
+	return c.accessLog.list()
+}
+
+// DryRun 在克隆的执行器上预演策略变更，报告结果发生变化的请求，不影响线上策略
+// requests为空时使用最近的鉴权请求
+func (c *CasbinEnforcer) DryRun(change PolicyChange, requests [][]string) (*DryRunResult, error) {
+	if len(change.Add) == 0 && len(change.Remove) == 0 {
+		return nil, errors.New("策略变更不能为空")
+	}
+	if err := c.ValidatePolicies(append(append([]PolicyLine{}, change.Add...), change.Remove...)); err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		requests = c.RecentRequests()
+	}
+
+	scratch, err := c.clone()
+	if err != nil {
+		return nil, err
+	}
+	before, err := enforceAll(scratch, requests)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyChange(scratch, change); err != nil {
+		return nil, err
+	}
+	after, err := enforceAll(scratch, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DryRunResult{Total: len(requests), Granted: make([]DecisionFlip, 0), Revoked: make([]DecisionFlip, 0)}
+	for i, request := range requests {
+		if before[i] == after[i] {
+			continue
+		}
+		flip := DecisionFlip{Request: request, Before: before[i], After: after[i]}
+		if after[i] {
+			result.Granted = append(result.Granted, flip)
+		} else {
+			result.Revoked = append(result.Revoked, flip)
+		}
+	}
+	return result, nil
+}
+
+// applyChange 先删除后新增，角色继承关系随g规则自动更新
+func applyChange(e *casbin.Enforcer, change PolicyChange) error {
+	for _, line := range change.Remove {
+		var err error
+		if line.Ptype[:1] == "g" {
+			_, err = e.RemoveNamedGroupingPolicy(line.Ptype, line.Rule)
+		} else {
+			_, err = e.RemoveNamedPolicy(line.Ptype, line.Rule)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, line := range change.Add {
+		var err error
+		if line.Ptype[:1] == "g" {
+			_, err = e.AddNamedGroupingPolicy(line.Ptype, line.Rule)
+		} else {
+			_, err = e.AddNamedPolicy(line.Ptype, line.Rule)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func enforceAll(e *casbin.Enforcer, requests [][]string) ([]bool, error) {
+	results := make([]bool, len(requests))
+	for i, request := range requests {
+		args := make([]interface{}, len(request))
+		for j, v := range request {
+			args[j] = v
+		}
+		ok, err := e.Enforce(args...)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = ok
+	}
+	return results, nil
+}
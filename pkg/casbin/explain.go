@@ -0,0 +1,206 @@
+package casbin
+
+import (
+	"fmt"
+	"go_casbin/internal/logger"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// Explanation 鉴权决策说明
+type Explanation struct {
+	Request         []string   `json:"request"`            // 请求参数
+	Subjects        []string   `json:"subjects,omitempty"` // 参与鉴权的全部主体
+	Subject         string     `json:"subject,omitempty"`  // 决定结果的主体
+	Allowed         bool       `json:"allowed"`            // 决策结果
+	Policy          []string   `json:"policy"`             // 决定结果的策略
+	MatchedPolicies [][]string `json:"matched_policies"`   // 所有能匹配该请求的策略
+	RoleChain       []string   `json:"role_chain"`         // 从主体到决定性策略主体的角色继承链
+	Reason          string     `json:"reason"`             // 决策原因
+}
+
+// Explain 解释一次鉴权的结果：命中的策略以及经过的角色链，不读写缓存
+func (c *CasbinEnforcer) Explain(rvals ...string) (*Explanation, error) {
+	if len(rvals) == 0 {
+		return nil, fmt.Errorf("请求参数不能为空")
+	}
+	args := toArgs(rvals)
+	// 按需加载模式下先加载请求的域，否则未加载的租户总是解释为没有策略
+	c.ensureRequestDomain(args)
+	ok, policy, err := c.enforcer().EnforceEx(args...)
+	if err != nil {
+		logger.ErrorWithErr("Casbin解释鉴权失败", err, logger.Field("rvals", rvals))
+		return nil, err
+	}
+
+	explanation := &Explanation{Request: rvals, Allowed: ok, Policy: policy}
+	domain := c.requestDomain(rvals)
+	if len(policy) > 0 {
+		explanation.RoleChain = c.roleChain(rvals[0], policy[0], domain)
+	}
+	explanation.MatchedPolicies, err = c.matchedPolicies(rvals, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case ok:
+		explanation.Reason = fmt.Sprintf("经由 %s 命中允许策略 [%s]", strings.Join(explanation.RoleChain, " -> "), strings.Join(policy, ", "))
+	case len(policy) > 0:
+		explanation.Reason = fmt.Sprintf("经由 %s 命中拒绝策略 [%s]", strings.Join(explanation.RoleChain, " -> "), strings.Join(policy, ", "))
+	default:
+		explanation.Reason = "没有匹配的策略，默认拒绝"
+	}
+	return explanation, nil
+}
+
+// ExplainSubjects 按CasbinAuth相同的多主体合并规则解释鉴权结果，rvals为主体之后的请求参数
+// 对决定结果的主体给出命中的策略和角色链，没有主体决定结果时解释第一个主体
+func (c *CasbinEnforcer) ExplainSubjects(strategy Strategy, subjects []string, rvals ...string) (*Explanation, error) {
+	if len(subjects) == 0 {
+		return nil, fmt.Errorf("主体不能为空")
+	}
+	args := toArgs(rvals)
+	c.ensureRequestDomain(append([]interface{}{subjects[0]}, args...))
+	c.checkGrants(subjects, time.Now())
+	decision, err := mergeSubjects(strategy, subjects, func(sub string) (bool, []string, error) {
+		return c.enforcer().EnforceEx(append([]interface{}{sub}, args...)...)
+	})
+	if err != nil {
+		logger.ErrorWithErr("Casbin解释鉴权失败", err, logger.Field("subjects", subjects), logger.Field("rvals", rvals))
+		return nil, err
+	}
+
+	sub := decision.Subject
+	if sub == "" {
+		sub = subjects[0]
+	}
+	explanation, err := c.Explain(append([]string{sub}, rvals...)...)
+	if err != nil {
+		return nil, err
+	}
+	explanation.Subjects = subjects
+	explanation.Subject = decision.Subject
+	explanation.Allowed = decision.Allowed
+	if decision.Subject == "" {
+		explanation.Reason = decision.Reason
+	}
+	return explanation, nil
+}
+
+// toArgs 把字符串请求参数转换为Enforce的参数
+func toArgs(rvals []string) []interface{} {
+	args := make([]interface{}, len(rvals))
+	for i, v := range rvals {
+		args[i] = v
+	}
+	return args
+}
+
+// requestDomain 多租户模型下请求的第二个参数为域
+func (c *CasbinEnforcer) requestDomain(rvals []string) []string {
+	if c.domainEnable && len(rvals) > 1 {
+		return []string{rvals[1]}
+	}
+	return nil
+}
+
+// roleChain 广度优先查找从sub到target的角色继承路径
+func (c *CasbinEnforcer) roleChain(sub, target string, domain []string) []string {
+	if sub == target {
+		return []string{sub}
+	}
+	prev := map[string]string{sub: ""}
+	queue := []string{sub}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		roles, err := c.enforcer().GetRolesForUser(current, domain...)
+		if err != nil {
+			return []string{sub}
+		}
+		for _, role := range roles {
+			if _, visited := prev[role]; visited {
+				continue
+			}
+			prev[role] = current
+			if role == target {
+				chain := []string{role}
+				for node := current; node != ""; node = prev[node] {
+					chain = append([]string{node}, chain...)
+				}
+				return chain
+			}
+			queue = append(queue, role)
+		}
+	}
+	return []string{sub}
+}
+
+// matchedPolicies 在独立的执行器中逐条验证主体及其角色的策略，找出所有能匹配该请求的策略
+func (c *CasbinEnforcer) matchedPolicies(rvals []string, domain []string) ([][]string, error) {
+	subjects, err := c.enforcer().GetImplicitRolesForUser(rvals[0], domain...)
+	if err != nil {
+		return nil, err
+	}
+	subjects = append(subjects, rvals[0])
+
+	candidates := make([][]string, 0)
+	for _, sub := range subjects {
+		candidates = append(candidates, c.GetFilteredPolicy(0, sub)...)
+	}
+	if len(candidates) == 0 {
+		return [][]string{}, nil
+	}
+
+	scratch, err := c.clone()
+	if err != nil {
+		return nil, err
+	}
+	// 只保留角色继承关系，候选策略逐条加入验证
+	policies, err := scratch.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := scratch.RemovePolicies(policies); err != nil {
+		return nil, err
+	}
+	args := toArgs(rvals)
+	matched := make([][]string, 0)
+	for _, candidate := range candidates {
+		if _, err := scratch.AddPolicy(candidate); err != nil {
+			return nil, err
+		}
+		_, policy, err := scratch.EnforceEx(args...)
+		if err != nil {
+			return nil, err
+		}
+		if len(policy) > 0 {
+			matched = append(matched, candidate)
+		}
+		if _, err := scratch.RemovePolicy(candidate); err != nil {
+			return nil, err
+		}
+	}
+	return matched, nil
+}
+
+// clone 复制当前模型和策略到独立的内存执行器，对其修改不会影响线上执行器
+func (c *CasbinEnforcer) clone() (*casbin.Enforcer, error) {
+	lock := c.enforcer().GetLock()
+	lock.RLock()
+	m := c.enforcer().GetModel().Copy()
+	lock.RUnlock()
+
+	e, err := casbin.NewEnforcer(m)
+	if err != nil {
+		return nil, err
+	}
+	c.addFunctions(e)
+	if err := e.BuildRoleLinks(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
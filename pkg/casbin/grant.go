@@ -0,0 +1,468 @@
+package casbin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_casbin/internal/logger"
+	"go_casbin/internal/model/audit"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"gorm.io/gorm"
+)
+
+// 限时角色授权状态
+const (
+	GrantPending = "pending" // 未到生效时间
+	GrantActive  = "active"  // 生效中，已写入g策略
+	GrantExpired = "expired" // 已过期，g策略已删除
+	GrantRevoked = "revoked" // 已撤销
+)
+
+// defaultGrantInterval 后台检查限时授权的默认间隔
+const defaultGrantInterval = time.Minute
+
+// RoleGrant 限时角色授权：在[StartAt, EndAt)内为用户添加g策略，到期后由后台任务或鉴权时触发删除
+type RoleGrant struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	User      string     `gorm:"column:user_id;size:100;index" json:"user"` // 用户
+	Role      string     `gorm:"size:100" json:"role"`                      // 角色
+	Domain    string     `gorm:"size:100" json:"domain,omitempty"`          // 域，启用多租户时有效
+	StartAt   time.Time  `gorm:"index" json:"start_at"`                     // 生效时间
+	EndAt     *time.Time `gorm:"index" json:"end_at,omitempty"`             // 过期时间，为空表示不过期
+	Status    string     `gorm:"size:20;index" json:"status"`               // 状态
+	LinkAdded bool       `json:"link_added"`                                // 生效时是否由本授权添加了g策略，过期时只删除自己添加的
+	Operator  string     `gorm:"size:100" json:"operator"`                  // 操作人
+	Reason    string     `gorm:"size:255" json:"reason"`                    // 授权原因
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (RoleGrant) TableName() string {
+	return "casbin_role_grant"
+}
+
+// RoleGrantRequest 创建限时授权的参数
+type RoleGrantRequest struct {
+	User     string
+	Role     string
+	Domain   string
+	StartAt  time.Time  // 为零值时立即生效
+	EndAt    *time.Time // 为空表示不过期
+	Operator string
+	Reason   string
+}
+
+// roleGrants 限时授权的存储和内存索引，索引只保存待生效和生效中的授权，供鉴权时快速判断
+type roleGrants struct {
+	db      *gorm.DB // 授权表所在数据库
+	auditDB *gorm.DB // 审计日志所在数据库
+
+	transition sync.Mutex  // 串行执行状态变更
+	syncing    atomic.Bool // 鉴权触发的后台处理是否在执行
+	mu         sync.RWMutex
+	byUser     map[string][]RoleGrant
+}
+
+var errGrantDisabled = errors.New("未启用限时角色授权")
+
+// EnableRoleGrants 启用限时角色授权；数据库模式下授权与策略保存在同一个库，审计日志写入db
+func (c *CasbinEnforcer) EnableRoleGrants(db *gorm.DB) error {
+	if db == nil {
+		return errors.New("限时角色授权需要数据库连接")
+	}
+	grantDB := db
+	if c.adapter != nil {
+		grantDB = c.adapter.DB()
+	}
+	if err := grantDB.AutoMigrate(&RoleGrant{}); err != nil {
+		logger.ErrorWithErr("初始化限时授权表失败", err)
+		return err
+	}
+	if err := db.AutoMigrate(&audit.AuditLog{}); err != nil {
+		logger.ErrorWithErr("初始化审计日志表失败", err)
+		return err
+	}
+	c.grants = &roleGrants{db: grantDB, auditDB: db}
+	return c.grants.reload()
+}
+
+// reload 从数据库重建内存索引
+func (g *roleGrants) reload() error {
+	var grants []RoleGrant
+	if err := g.db.Where("status IN ?", []string{GrantPending, GrantActive}).Find(&grants).Error; err != nil {
+		logger.ErrorWithErr("加载限时授权失败", err)
+		return err
+	}
+	byUser := make(map[string][]RoleGrant)
+	for _, grant := range grants {
+		byUser[grant.User] = append(byUser[grant.User], grant)
+	}
+	g.mu.Lock()
+	g.byUser = byUser
+	g.mu.Unlock()
+	return nil
+}
+
+// due 授权在now时刻应转换到的状态，不需要转换时返回空
+func (grant *RoleGrant) due(now time.Time) string {
+	switch grant.Status {
+	case GrantPending:
+		if grant.EndAt != nil && !now.Before(*grant.EndAt) {
+			return GrantExpired
+		}
+		if !now.Before(grant.StartAt) {
+			return GrantActive
+		}
+	case GrantActive:
+		if grant.EndAt != nil && !now.Before(*grant.EndAt) {
+			return GrantExpired
+		}
+	}
+	return ""
+}
+
+// GrantRole 创建限时角色授权，生效时间已到时立即写入g策略
+func (c *CasbinEnforcer) GrantRole(req RoleGrantRequest) (*RoleGrant, error) {
+	if c.grants == nil {
+		return nil, errGrantDisabled
+	}
+	if req.User == "" || req.Role == "" {
+		return nil, errors.New("用户和角色不能为空")
+	}
+	if c.domainEnable && req.Domain == "" {
+		return nil, errors.New("已启用多租户，domain不能为空")
+	}
+	now := time.Now()
+	if req.StartAt.IsZero() {
+		req.StartAt = now
+	}
+	if req.EndAt != nil {
+		if !req.EndAt.After(req.StartAt) {
+			return nil, errors.New("过期时间必须晚于生效时间")
+		}
+		if !req.EndAt.After(now) {
+			return nil, errors.New("过期时间必须晚于当前时间")
+		}
+	}
+	grant := &RoleGrant{
+		User:     req.User,
+		Role:     req.Role,
+		Domain:   req.Domain,
+		StartAt:  req.StartAt,
+		EndAt:    req.EndAt,
+		Status:   GrantPending,
+		Operator: req.Operator,
+		Reason:   req.Reason,
+	}
+	if err := c.grants.db.Create(grant).Error; err != nil {
+		logger.ErrorWithErr("保存限时授权失败", err, logger.String("user", req.User), logger.String("role", req.Role))
+		return nil, err
+	}
+	c.grants.audit("role_grant_create", grant.ID, nil, grant)
+	if grant.due(now) == GrantActive {
+		if err := c.transitGrant(*grant, GrantActive, req.Operator); err != nil {
+			return nil, err
+		}
+		if err := c.grants.db.First(grant, grant.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := c.grants.reload(); err != nil {
+		return nil, err
+	}
+	logger.Info("已创建限时角色授权", logger.Field("id", grant.ID), logger.String("user", grant.User), logger.String("role", grant.Role), logger.String("status", grant.Status))
+	return grant, nil
+}
+
+// RevokeGrant 撤销限时授权，生效中的授权同时删除g策略
+func (c *CasbinEnforcer) RevokeGrant(id uint, operator string) (*RoleGrant, error) {
+	if c.grants == nil {
+		return nil, errGrantDisabled
+	}
+	var grant RoleGrant
+	if err := c.grants.db.First(&grant, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("授权 %d 不存在", id)
+		}
+		return nil, err
+	}
+	if grant.Status != GrantPending && grant.Status != GrantActive {
+		return nil, fmt.Errorf("授权 %d 已%s，不能撤销", id, grant.Status)
+	}
+	if err := c.transitGrant(grant, GrantRevoked, operator); err != nil {
+		return nil, err
+	}
+	if err := c.grants.db.First(&grant, id).Error; err != nil {
+		return nil, err
+	}
+	return &grant, c.grants.reload()
+}
+
+// ListGrants 分页查询限时授权，user、status为空时不过滤
+func (c *CasbinEnforcer) ListGrants(user, status string, page, pageSize int) ([]RoleGrant, int64, error) {
+	if c.grants == nil {
+		return nil, 0, errGrantDisabled
+	}
+	query := c.grants.db.Model(&RoleGrant{})
+	if user != "" {
+		query = query.Where("user_id = ?", user)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	grants := make([]RoleGrant, 0)
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&grants).Error
+	return grants, total, err
+}
+
+// SyncGrants 使到达生效时间的授权生效、到达过期时间的授权过期，多实例同时执行时只有一个实例变更成功
+func (c *CasbinEnforcer) SyncGrants(now time.Time) error {
+	if c.grants == nil {
+		return errGrantDisabled
+	}
+	var grants []RoleGrant
+	err := c.grants.db.
+		Where("status = ? AND start_at <= ?", GrantPending, now).
+		Or("status IN ? AND end_at IS NOT NULL AND end_at <= ?", []string{GrantPending, GrantActive}, now).
+		Order("id").Find(&grants).Error
+	if err != nil {
+		logger.ErrorWithErr("查询到期的限时授权失败", err)
+		return err
+	}
+	for _, grant := range grants {
+		if to := grant.due(now); to != "" {
+			if err := c.transitGrant(grant, to, "system"); err != nil {
+				logger.ErrorWithErr("变更限时授权状态失败", err, logger.Field("id", grant.ID), logger.String("to", to))
+			}
+		}
+	}
+	return c.grants.reload()
+}
+
+// StartGrantScheduler 启动后台任务定期执行SyncGrants，返回停止函数
+func (c *CasbinEnforcer) StartGrantScheduler(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = defaultGrantInterval
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				_ = c.SyncGrants(now)
+			}
+		}
+	}()
+	logger.Info("限时角色授权检查任务已启动", logger.Duration("interval", interval))
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// checkGrants 鉴权时处理主体到期未变更的限时授权，不依赖后台任务的执行间隔
+// 已过期且没有其他生效中授权的g策略立即从角色继承关系中隐藏，本次鉴权即不再继承该角色；
+// 状态变更和删除g策略需要写数据库，交给后台执行，同一时刻最多一个处理任务，期间发现的到期授权由下一次鉴权再次触发
+func (c *CasbinEnforcer) checkGrants(subjects []string, now time.Time) {
+	g := c.grants
+	if g == nil {
+		return
+	}
+	var due []RoleGrant
+	var expired [][]string
+	g.mu.RLock()
+	for _, sub := range subjects {
+		grants := g.byUser[sub]
+		for _, grant := range grants {
+			to := grant.due(now)
+			if to == "" {
+				continue
+			}
+			due = append(due, grant)
+			if to == GrantExpired && grant.Status == GrantActive && grant.LinkAdded && !hasLiveGrant(grants, grant, now) {
+				expired = append(expired, c.grantRule(grant))
+			}
+		}
+	}
+	g.mu.RUnlock()
+	if len(expired) > 0 {
+		c.hideRoleLinks(expired)
+	}
+	if len(due) == 0 || !g.syncing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer g.syncing.Store(false)
+		for _, grant := range due {
+			if err := c.transitGrant(grant, grant.due(now), "system"); err != nil {
+				logger.ErrorWithErr("变更限时授权状态失败", err, logger.Field("id", grant.ID))
+			}
+		}
+		_ = g.reload()
+	}()
+}
+
+// hasLiveGrant 同一用户角色是否还有其他生效中且未到期的授权，有则到期的g策略会交给它持有
+func hasLiveGrant(grants []RoleGrant, expired RoleGrant, now time.Time) bool {
+	for _, grant := range grants {
+		if grant.ID != expired.ID && grant.Role == expired.Role && grant.Domain == expired.Domain &&
+			grant.Status == GrantActive && grant.due(now) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// grantRule 授权对应的g策略
+func (c *CasbinEnforcer) grantRule(grant RoleGrant) []string {
+	if c.domainEnable {
+		return []string{grant.User, grant.Role, grant.Domain}
+	}
+	return []string{grant.User, grant.Role}
+}
+
+// hideRoleLinks 只从角色管理器中删除继承关系，g策略仍保留在模型和存储中，由后台的状态变更删除
+// 状态变更完成前重新加载策略会恢复继承关系，下一次鉴权时再次隐藏
+func (c *CasbinEnforcer) hideRoleLinks(rules [][]string) {
+	c.swapMu.RLock()
+	defer c.swapMu.RUnlock()
+	e := c.enforcer()
+	lock := e.GetLock()
+
+	var linked [][]string
+	lock.RLock()
+	for _, rule := range rules {
+		if ok, err := e.Enforcer.GetRoleManager().HasLink(rule[0], rule[1], rule[2:]...); err == nil && ok {
+			linked = append(linked, rule)
+		}
+	}
+	lock.RUnlock()
+	if len(linked) == 0 {
+		return
+	}
+
+	lock.Lock()
+	err := e.Enforcer.BuildIncrementalRoleLinks(model.PolicyRemove, "g", linked)
+	lock.Unlock()
+	if err != nil {
+		logger.ErrorWithErr("隐藏到期授权的角色继承失败", err, logger.Field("rules", linked))
+	}
+	c.invalidateRules("g", linked...)
+}
+
+// transitGrant 先以条件更新抢占状态变更，成功后同步g策略并记录审计日志
+func (c *CasbinEnforcer) transitGrant(grant RoleGrant, to, operator string) error {
+	g := c.grants
+	g.transition.Lock()
+	defer g.transition.Unlock()
+
+	old := grant
+	result := g.db.Model(&RoleGrant{}).Where("id = ? AND status = ?", grant.ID, old.Status).Update("status", to)
+	if result.Error != nil {
+		logger.ErrorWithErr("更新限时授权状态失败", result.Error, logger.Field("id", grant.ID))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 其他实例已处理，g策略的变更经由watcher同步
+		return nil
+	}
+	grant.Status = to
+	if err := c.syncGrantLink(&grant, old.Status); err != nil {
+		// g策略变更失败时恢复状态，下次检查时重试
+		if rollbackErr := g.db.Model(&RoleGrant{}).Where("id = ?", grant.ID).Update("status", old.Status).Error; rollbackErr != nil {
+			logger.ErrorWithErr("恢复限时授权状态失败", rollbackErr, logger.Field("id", grant.ID), logger.String("status", old.Status))
+		}
+		return err
+	}
+	if grant.LinkAdded != old.LinkAdded {
+		if err := g.db.Model(&RoleGrant{}).Where("id = ?", grant.ID).Update("link_added", grant.LinkAdded).Error; err != nil {
+			return err
+		}
+	}
+	g.audit("role_grant_"+to, grant.ID, &old, &grant)
+	logger.Info("限时角色授权状态变更",
+		logger.Field("id", grant.ID),
+		logger.String("user", grant.User),
+		logger.String("role", grant.Role),
+		logger.String("from", old.Status),
+		logger.String("to", to),
+		logger.String("operator", operator),
+	)
+	return nil
+}
+
+// syncGrantLink 生效时添加g策略；过期或撤销时只删除本授权添加的g策略
+func (c *CasbinEnforcer) syncGrantLink(grant *RoleGrant, from string) error {
+	switch grant.Status {
+	case GrantActive:
+		added, err := c.addGrantLink(*grant)
+		if err != nil {
+			return err
+		}
+		grant.LinkAdded = added
+	case GrantExpired, GrantRevoked:
+		if from != GrantActive || !grant.LinkAdded {
+			return nil
+		}
+		// 同一用户角色还有其他生效中的授权时，把g策略交给它，暂不删除
+		var heir RoleGrant
+		err := c.grants.db.Where("id <> ? AND user_id = ? AND role = ? AND domain = ? AND status = ?",
+			grant.ID, grant.User, grant.Role, grant.Domain, GrantActive).
+			Order("id").Limit(1).Find(&heir).Error
+		if err != nil {
+			return err
+		}
+		if heir.ID != 0 && heir.due(time.Now()) == "" {
+			if err := c.grants.db.Model(&RoleGrant{}).Where("id = ?", heir.ID).Update("link_added", true).Error; err != nil {
+				return err
+			}
+		} else if err := c.removeGrantLink(*grant); err != nil {
+			return err
+		}
+		grant.LinkAdded = false
+	}
+	return nil
+}
+
+func (c *CasbinEnforcer) addGrantLink(grant RoleGrant) (bool, error) {
+	if c.domainEnable {
+		return c.AddRoleForUserInDomain(grant.User, grant.Role, grant.Domain)
+	}
+	return c.AddRoleForUser(grant.User, grant.Role)
+}
+
+func (c *CasbinEnforcer) removeGrantLink(grant RoleGrant) error {
+	var err error
+	if c.domainEnable {
+		_, err = c.DeleteRoleForUserInDomain(grant.User, grant.Role, grant.Domain)
+	} else {
+		_, err = c.DeleteRoleForUser(grant.User, grant.Role)
+	}
+	return err
+}
+
+// audit 记录授权变更，失败只记日志，不影响授权本身
+func (g *roleGrants) audit(action string, id uint, oldGrant, newGrant *RoleGrant) {
+	oldData, _ := json.Marshal(oldGrant)
+	newData, _ := json.Marshal(newGrant)
+	entry := audit.AuditLog{
+		Action:    action,
+		TableName: RoleGrant{}.TableName(),
+		RecordID:  id,
+		OldData:   string(oldData),
+		NewData:   string(newData),
+	}
+	if err := g.auditDB.Create(&entry).Error; err != nil {
+		logger.ErrorWithErr("写入审计日志失败", err, logger.String("action", action), logger.Field("id", id))
+	}
+}
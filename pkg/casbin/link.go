@@ -0,0 +1,144 @@
+package casbin
+
+import (
+	"go_casbin/internal/logger"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RoleLink 用户到角色的一条g策略，Domain仅在启用多租户时有效
+type RoleLink struct {
+	User   string `json:"user"`
+	Role   string `json:"role"`
+	Domain string `json:"domain,omitempty"`
+}
+
+// AddRoleLink 添加g策略，由调用方(如账户角色)持有
+// g策略已存在且由生效中的限时授权添加时改由调用方持有，授权到期后不再删除
+func (c *CasbinEnforcer) AddRoleLink(link RoleLink) (bool, error) {
+	var ok bool
+	var err error
+	if c.domainEnable {
+		c.ensureDomain(link.Domain)
+		ok, err = c.AddRoleForUserInDomain(link.User, link.Role, link.Domain)
+	} else {
+		ok, err = c.AddRoleForUser(link.User, link.Role)
+	}
+	if err != nil || ok || c.grants == nil {
+		return ok, err
+	}
+	return false, c.handOverLink(link)
+}
+
+// RemoveRoleLink 删除调用方持有的g策略；同一用户角色还有生效中的限时授权时交由授权持有，不删除
+func (c *CasbinEnforcer) RemoveRoleLink(link RoleLink) (bool, error) {
+	if c.domainEnable {
+		c.ensureDomain(link.Domain)
+	}
+	if c.grants != nil {
+		kept, err := c.takeOverLink(link, time.Now())
+		if err != nil || kept {
+			return false, err
+		}
+	}
+	if c.domainEnable {
+		return c.DeleteRoleForUserInDomain(link.User, link.Role, link.Domain)
+	}
+	return c.DeleteRoleForUser(link.User, link.Role)
+}
+
+// RoleLinks 用户直接拥有的全部g策略
+func (c *CasbinEnforcer) RoleLinks(user string) []RoleLink {
+	rules, err := c.enforcer().GetFilteredGroupingPolicy(0, user)
+	if err != nil {
+		logger.ErrorWithErr("获取用户角色失败", err, logger.String("user", user))
+		return nil
+	}
+	return toRoleLinks(rules)
+}
+
+// AllRoleLinks 全部g策略，包括角色之间的继承
+func (c *CasbinEnforcer) AllRoleLinks() []RoleLink {
+	return toRoleLinks(c.GetGroupingPolicy())
+}
+
+// GrantHeldLinks 由生效中的限时授权添加的g策略，这些策略不属于其他来源
+func (c *CasbinEnforcer) GrantHeldLinks() (map[RoleLink]bool, error) {
+	held := make(map[RoleLink]bool)
+	if c.grants == nil {
+		return held, nil
+	}
+	var grants []RoleGrant
+	if err := c.grants.db.Where("status = ? AND link_added = ?", GrantActive, true).Find(&grants).Error; err != nil {
+		logger.ErrorWithErr("查询生效中的限时授权失败", err)
+		return nil, err
+	}
+	for _, grant := range grants {
+		link := RoleLink{User: grant.User, Role: grant.Role}
+		if c.domainEnable {
+			link.Domain = grant.Domain
+		}
+		held[link] = true
+	}
+	return held, nil
+}
+
+func toRoleLinks(rules [][]string) []RoleLink {
+	links := make([]RoleLink, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule) < 2 {
+			continue
+		}
+		link := RoleLink{User: rule[0], Role: rule[1]}
+		if len(rule) > 2 {
+			link.Domain = rule[2]
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// grantsOfLink 与g策略对应的生效中的限时授权，未启用多租户时忽略授权中的域
+func (c *CasbinEnforcer) grantsOfLink(link RoleLink) *gorm.DB {
+	query := c.grants.db.Model(&RoleGrant{}).Where("user_id = ? AND role = ? AND status = ?", link.User, link.Role, GrantActive)
+	if c.domainEnable {
+		query = query.Where("domain = ?", link.Domain)
+	}
+	return query
+}
+
+// handOverLink 其他来源接管已存在的g策略：生效中的授权不再持有，到期时不删除
+func (c *CasbinEnforcer) handOverLink(link RoleLink) error {
+	result := c.grantsOfLink(link).Where("link_added = ?", true).Update("link_added", false)
+	if result.Error != nil {
+		logger.ErrorWithErr("转移限时授权的g策略失败", result.Error, logger.Field("link", link))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	return c.grants.reload()
+}
+
+// takeOverLink 其他来源放弃g策略时由仍在生效的授权接管，返回true表示g策略应保留
+func (c *CasbinEnforcer) takeOverLink(link RoleLink, now time.Time) (bool, error) {
+	var heir RoleGrant
+	if err := c.grantsOfLink(link).Order("id").Limit(1).Find(&heir).Error; err != nil {
+		logger.ErrorWithErr("查询限时授权失败", err, logger.Field("link", link))
+		return false, err
+	}
+	if heir.ID == 0 || heir.due(now) != "" {
+		return false, nil
+	}
+	if !heir.LinkAdded {
+		if err := c.grants.db.Model(&RoleGrant{}).Where("id = ?", heir.ID).Update("link_added", true).Error; err != nil {
+			logger.ErrorWithErr("转移g策略给限时授权失败", err, logger.Field("id", heir.ID))
+			return false, err
+		}
+		if err := c.grants.reload(); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
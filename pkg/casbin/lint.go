@@ -0,0 +1,361 @@
+package casbin
+
+import (
+	"encoding/csv"
+	"fmt"
+	"go_casbin/pkg/path"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 策略检查发现的问题类型
+const (
+	LintDuplicate = "duplicate"  // 重复的规则
+	LintOrphan    = "orphan"     // 没有任何用户的角色
+	LintEmptyRole = "empty-role" // 分配给用户但没有任何权限的角色
+	LintCycle     = "cycle"      // 角色继承成环
+	LintShadowed  = "shadowed"   // 被更宽的允许规则完全覆盖的允许规则
+)
+
+// LintIssue 策略检查发现的一个问题
+type LintIssue struct {
+	Kind    string   `json:"kind"`
+	Ptype   string   `json:"ptype"`
+	Rule    []string `json:"rule"`
+	Related []string `json:"related,omitempty"` // 相关的规则，如覆盖它的规则
+	Reason  string   `json:"reason"`
+}
+
+// LintPolicy 基于GetPolicy、GetGroupingPolicy和GetAllRoles检查p和g策略：重复规则、没有用户的角色、没有权限的角色、角色继承环、被通配规则覆盖的规则
+// 加载到内存时重复规则已被忽略，重复规则从策略存储(数据库表或策略文件)中原样读取
+func (c *CasbinEnforcer) LintPolicy() ([]LintIssue, error) {
+	raw, err := c.rawRules()
+	if err != nil {
+		return nil, err
+	}
+	issues := lintDuplicates(raw)
+
+	graph := newRoleGraph(c.GetGroupingPolicy())
+	policies := c.GetPolicy()
+	subIndex, domIndex := c.policyIndex("p_sub"), c.policyIndex("p_dom")
+	withPolicy := make(map[string]bool)
+	for _, rule := range policies {
+		if subIndex >= 0 && subIndex < len(rule) {
+			withPolicy[rule[subIndex]] = true
+		}
+	}
+	issues = append(issues, graph.cycles()...)
+	issues = append(issues, graph.orphans(withPolicy)...)
+	issues = append(issues, graph.emptyRoles(c.GetAllRoles(), withPolicy)...)
+	issues = append(issues, c.lintShadowed(policies, graph, subIndex, domIndex)...)
+	return issues, nil
+}
+
+// rawRules 策略存储中的全部规则，首个字段为ptype
+func (c *CasbinEnforcer) rawRules() ([][]string, error) {
+	if c.adapter != nil {
+		var lines []CasbinRule
+		if err := c.adapter.DB().Find(&lines).Error; err != nil {
+			return nil, err
+		}
+		rules := make([][]string, 0, len(lines))
+		for _, line := range lines {
+			rules = append(rules, line.toArray())
+		}
+		return rules, nil
+	}
+	if c.options.Driver != "file" {
+		return nil, nil
+	}
+	file := c.options.DataSource
+	if !filepath.IsAbs(file) {
+		absPath, err := path.GetAbsolutePath(file)
+		if err != nil {
+			return nil, err
+		}
+		file = absPath
+	}
+	return readPolicyFile(file)
+}
+
+// readPolicyFile 按file-adapter的规则读取策略文件：忽略空行和#注释，字段去掉首尾空格
+func readPolicyFile(file string) ([][]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([][]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		reader := csv.NewReader(strings.NewReader(line))
+		reader.TrimLeadingSpace = true
+		rule, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("解析策略 %q 失败: %w", line, err)
+		}
+		for i := range rule {
+			rule[i] = strings.TrimSpace(rule[i])
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// lintDuplicates 找出重复的规则，每组重复只报告一次
+func lintDuplicates(raw [][]string) []LintIssue {
+	counts := make(map[string]int)
+	first := make(map[string][]string)
+	order := make([]string, 0)
+	for _, rule := range raw {
+		if len(rule) < 2 {
+			continue
+		}
+		key := strings.Join(rule, "\x00")
+		if counts[key] == 0 {
+			first[key] = rule
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+	issues := make([]LintIssue, 0)
+	for _, key := range order {
+		if counts[key] > 1 {
+			rule := first[key]
+			issues = append(issues, LintIssue{Kind: LintDuplicate, Ptype: rule[0], Rule: rule[1:], Reason: fmt.Sprintf("规则重复 %d 次", counts[key])})
+		}
+	}
+	return issues
+}
+
+// roleGraph g策略构成的继承关系，不区分域
+type roleGraph struct {
+	parents map[string][]string // 主体 -> 直接继承的角色
+	members map[string][]string // 角色 -> 直接成员(用户或角色)
+}
+
+func newRoleGraph(rules [][]string) *roleGraph {
+	g := &roleGraph{parents: make(map[string][]string), members: make(map[string][]string)}
+	for _, rule := range rules {
+		if len(rule) < 2 {
+			continue
+		}
+		g.parents[rule[0]] = append(g.parents[rule[0]], rule[1])
+		g.members[rule[1]] = append(g.members[rule[1]], rule[0])
+	}
+	return g
+}
+
+// isRole 作为g策略的角色出现过
+func (g *roleGraph) isRole(name string) bool {
+	return len(g.members[name]) > 0
+}
+
+// walk 从start出发沿next广度遍历，返回经过的全部节点(不含start)
+func walk(start string, next map[string][]string) []string {
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+	result := make([]string, 0)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, n := range next[current] {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+			result = append(result, n)
+			queue = append(queue, n)
+		}
+	}
+	return result
+}
+
+// users 角色经继承关系覆盖的全部用户(自身不是角色的成员)
+func (g *roleGraph) users(role string) []string {
+	users := make([]string, 0)
+	for _, member := range walk(role, g.members) {
+		if !g.isRole(member) {
+			users = append(users, member)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// cycles 找出继承环，每个环只报告一次
+func (g *roleGraph) cycles() []LintIssue {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	seen := make(map[string]bool)
+	issues := make([]LintIssue, 0)
+	var stack []string
+	var visit func(node string)
+	visit = func(node string) {
+		state[node] = visiting
+		stack = append(stack, node)
+		for _, parent := range g.parents[node] {
+			switch state[parent] {
+			case unvisited:
+				visit(parent)
+			case visiting:
+				start := len(stack) - 1
+				for stack[start] != parent {
+					start--
+				}
+				cycle := append(append([]string(nil), stack[start:]...), parent)
+				key := cycleKey(cycle[:len(cycle)-1])
+				if !seen[key] {
+					seen[key] = true
+					issues = append(issues, LintIssue{Kind: LintCycle, Ptype: "g", Rule: cycle, Reason: "角色继承成环: " + strings.Join(cycle, " -> ")})
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[node] = done
+	}
+	nodes := make([]string, 0, len(g.parents))
+	for node := range g.parents {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		if state[node] == unvisited {
+			visit(node)
+		}
+	}
+	return issues
+}
+
+// cycleKey 环的规范表示，与起点无关
+func cycleKey(nodes []string) string {
+	sorted := append([]string(nil), nodes...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "\x00")
+}
+
+// orphans 有策略但没有任何用户的主体：既不是任何g策略的角色，也没有继承角色，通常是已删除的角色
+// g策略中的角色沿成员关系最终都会到达用户，只有继承环中的角色例外，已由cycles报告
+func (g *roleGraph) orphans(withPolicy map[string]bool) []LintIssue {
+	subjects := make([]string, 0, len(withPolicy))
+	for sub := range withPolicy {
+		subjects = append(subjects, sub)
+	}
+	issues := make([]LintIssue, 0)
+	for _, sub := range sortedUnique(subjects) {
+		if !g.isRole(sub) && len(g.parents[sub]) == 0 {
+			issues = append(issues, LintIssue{Kind: LintOrphan, Ptype: "p", Rule: []string{sub}, Reason: fmt.Sprintf("主体 %s 有策略但没有任何用户继承，可能是已删除的角色(直接授权的用户可忽略)", sub)})
+		}
+	}
+	return issues
+}
+
+// emptyRoles 分配给用户、但自身及继承的角色都没有策略的角色
+func (g *roleGraph) emptyRoles(roles []string, withPolicy map[string]bool) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, role := range sortedUnique(roles) {
+		if withPolicy[role] {
+			continue
+		}
+		empty := true
+		for _, ancestor := range walk(role, g.parents) {
+			if withPolicy[ancestor] {
+				empty = false
+				break
+			}
+		}
+		users := g.users(role)
+		if !empty || len(users) == 0 {
+			continue
+		}
+		issues = append(issues, LintIssue{Kind: LintEmptyRole, Ptype: "g", Rule: []string{role}, Reason: fmt.Sprintf("角色 %s 没有任何权限，仍分配给 %d 个用户: %s", role, len(users), strings.Join(users, ", "))})
+	}
+	return issues
+}
+
+// lintShadowed 找出被同一主体或其继承角色的更宽允许规则完全覆盖的允许规则：obj被通配或正则覆盖，act相同或为*
+func (c *CasbinEnforcer) lintShadowed(policies [][]string, graph *roleGraph, subIndex, domIndex int) []LintIssue {
+	objIndex, actIndex, eftIndex := c.policyIndex("p_obj"), c.policyIndex("p_act"), c.policyIndex("p_eft")
+	if subIndex < 0 || objIndex < 0 || actIndex < 0 {
+		return nil
+	}
+	field := func(rule []string, i int) string {
+		if i < 0 || i >= len(rule) {
+			return ""
+		}
+		return rule[i]
+	}
+	bySubject := make(map[string][][]string)
+	for _, rule := range policies {
+		if field(rule, eftIndex) == "deny" {
+			continue
+		}
+		sub := field(rule, subIndex)
+		bySubject[sub] = append(bySubject[sub], rule)
+	}
+
+	issues := make([]LintIssue, 0)
+	for _, rule := range policies {
+		if field(rule, eftIndex) == "deny" {
+			continue
+		}
+		sub := field(rule, subIndex)
+		for _, owner := range append([]string{sub}, walk(sub, graph.parents)...) {
+			broader := findBroader(rule, bySubject[owner], func(a, b []string) bool {
+				return field(a, domIndex) == field(b, domIndex) &&
+					covers(field(b, objIndex), field(a, objIndex)) &&
+					(field(b, actIndex) == "*" || field(b, actIndex) == field(a, actIndex))
+			})
+			if broader != nil {
+				issues = append(issues, LintIssue{Kind: LintShadowed, Ptype: "p", Rule: rule, Related: broader, Reason: fmt.Sprintf("已被主体 %s 的规则 [%s] 覆盖", owner, strings.Join(broader, ", "))})
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// findBroader 在candidates中找出覆盖rule且不同于rule的规则
+func findBroader(rule []string, candidates [][]string, cover func(a, b []string) bool) []string {
+	for _, candidate := range candidates {
+		if strings.Join(candidate, "\x00") == strings.Join(rule, "\x00") {
+			continue
+		}
+		if cover(rule, candidate) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// covers 模式pattern是否覆盖obj能匹配的全部请求：相同；或obj不含通配、不是正则，且能被pattern匹配
+func covers(pattern, obj string) bool {
+	if pattern == obj {
+		return true
+	}
+	if strings.HasPrefix(obj, RegexPrefix) || strings.Contains(obj, "*") {
+		return false
+	}
+	return PathMatch(obj, pattern)
+}
+
+func sortedUnique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
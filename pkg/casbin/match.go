@@ -0,0 +1,73 @@
+package casbin
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+// RBACModel 内置的RBAC模型，请求格式为 (sub, obj, act)
+// obj为路由模板(如/api/v1/accounts/:id)，策略中的obj支持keyMatch2模式和regex:前缀的正则，act支持*
+const RBACModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && pathMatch(r.obj, p.obj) && (r.act == p.act || p.act == "*")
+`
+
+// RegexPrefix 策略obj以该前缀开头时按正则匹配，正则自动首尾锚定
+const RegexPrefix = "regex:"
+
+var (
+	regexMu    sync.RWMutex
+	regexCache = make(map[string]*regexp.Regexp)
+)
+
+// PathMatch 判断请求路径(或路由模板)是否匹配策略中的obj：regex:前缀按正则匹配，否则使用keyMatch2
+func PathMatch(path, pattern string) bool {
+	if expr, ok := strings.CutPrefix(pattern, RegexPrefix); ok {
+		re, err := compileRegex(expr)
+		return err == nil && re.MatchString(path)
+	}
+	return util.KeyMatch2(path, pattern)
+}
+
+// pathMatchFunc 注册到执行器的pathMatch函数
+func pathMatchFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return false, errors.New("pathMatch需要2个参数")
+	}
+	path, _ := args[0].(string)
+	pattern, _ := args[1].(string)
+	return PathMatch(path, pattern), nil
+}
+
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	regexMu.RLock()
+	re, ok := regexCache[expr]
+	regexMu.RUnlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	regexMu.Lock()
+	regexCache[expr] = re
+	regexMu.Unlock()
+	return re, nil
+}
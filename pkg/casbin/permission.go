@@ -0,0 +1,144 @@
+package casbin
+
+import (
+	"go_casbin/internal/logger"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AllowedPermission 主体可执行的一项(obj, act)
+type AllowedPermission struct {
+	Object string `json:"object"`         // 路由模板或菜单、按钮等资源
+	Action string `json:"action"`         // 操作
+	Name   string `json:"name,omitempty"` // 权限目录中的权限名，obj不是路由时为空
+}
+
+// EnforceMany 批量权限判断，每个请求的参数与Enforce相同，结果与请求一一对应
+func (c *CasbinEnforcer) EnforceMany(requests [][]interface{}) ([]bool, error) {
+	results := make([]bool, len(requests))
+	for i, rvals := range requests {
+		ok, _, err := c.enforceCached(rvals...)
+		if err != nil {
+			logger.ErrorWithErr("Casbin批量权限校验失败", err, logger.Field("rvals", rvals))
+			return nil, err
+		}
+		results[i] = ok
+	}
+	return results, nil
+}
+
+// AllowedPermissions 列出主体(用户本身及其角色)经角色继承后可执行的全部(obj, act)，prefix不为空时只返回obj以prefix开头的项
+// 候选项取自隐式权限：obj为路由模式时按权限目录展开为具体路由，act为*时展开为路由的方法；
+// 每个候选项再按strategy对全部主体鉴权，被显式deny覆盖或无法匹配的模式(如未展开的regex:)不会返回
+func (c *CasbinEnforcer) AllowedPermissions(strategy Strategy, subjects []string, domain, prefix string) ([]AllowedPermission, error) {
+	c.checkGrants(subjects, time.Now())
+	var domains []string
+	if domain != "" {
+		domains = []string{domain}
+		c.ensureDomain(domain)
+	}
+	objIndex, actIndex, eftIndex := c.policyIndex("p_obj"), c.policyIndex("p_act"), c.policyIndex("p_eft")
+	if objIndex < 0 || actIndex < 0 {
+		return []AllowedPermission{}, nil
+	}
+
+	seen := make(map[AllowedPermission]bool)
+	candidates := make([]AllowedPermission, 0)
+	addCandidate := func(p AllowedPermission) {
+		if !strings.HasPrefix(p.Object, prefix) || seen[p] {
+			return
+		}
+		seen[p] = true
+		candidates = append(candidates, p)
+	}
+	catalog := GetCatalog()
+	for _, sub := range uniqueSubjects(subjects) {
+		rules, err := c.enforcer().GetImplicitPermissionsForUser(sub, domains...)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range rules {
+			if objIndex >= len(rule) || actIndex >= len(rule) {
+				continue
+			}
+			if eftIndex >= 0 && eftIndex < len(rule) && rule[eftIndex] == "deny" {
+				continue
+			}
+			obj, act := rule[objIndex], rule[actIndex]
+			routes := []Permission(nil)
+			if isPathObject(obj) {
+				routes = catalog.Match(obj)
+			}
+			if len(routes) == 0 {
+				addCandidate(AllowedPermission{Object: obj, Action: act})
+				continue
+			}
+			for _, route := range routes {
+				if act == "*" || strings.EqualFold(act, route.Method) {
+					addCandidate(AllowedPermission{Object: route.Path, Action: route.Method, Name: route.Name})
+				}
+			}
+		}
+	}
+
+	allowed := make([]AllowedPermission, 0, len(candidates))
+	for _, p := range candidates {
+		ok, err := c.allowedForSubjects(strategy, subjects, domain, p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			allowed = append(allowed, p)
+		}
+	}
+	sort.Slice(allowed, func(i, j int) bool {
+		if allowed[i].Object != allowed[j].Object {
+			return allowed[i].Object < allowed[j].Object
+		}
+		return allowed[i].Action < allowed[j].Action
+	})
+	return allowed, nil
+}
+
+// allowedForSubjects 与EnforceSubjects相同的合并规则，但不记录访问，避免候选项混入dry-run的最近请求
+func (c *CasbinEnforcer) allowedForSubjects(strategy Strategy, subjects []string, domain string, p AllowedPermission) (bool, error) {
+	decision, err := mergeSubjects(strategy, subjects, func(sub string) (bool, []string, error) {
+		if domain != "" {
+			return c.enforceCached(sub, domain, p.Object, p.Action)
+		}
+		return c.enforceCached(sub, p.Object, p.Action)
+	})
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed, nil
+}
+
+// policyIndex p定义中token的位置，不存在时返回-1
+func (c *CasbinEnforcer) policyIndex(token string) int {
+	assertion, ok := c.enforcer().GetModel()["p"]["p"]
+	if !ok {
+		return -1
+	}
+	for i, t := range assertion.Tokens {
+		if t == token {
+			return i
+		}
+	}
+	return -1
+}
+
+// uniqueSubjects 去掉空主体和重复主体，保持顺序
+func uniqueSubjects(subjects []string) []string {
+	seen := make(map[string]bool, len(subjects))
+	result := make([]string, 0, len(subjects))
+	for _, sub := range subjects {
+		if sub == "" || seen[sub] {
+			continue
+		}
+		seen[sub] = true
+		result = append(result, sub)
+	}
+	return result
+}
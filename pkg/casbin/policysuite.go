@@ -0,0 +1,267 @@
+package casbin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicySuite 策略测试用例表，model、policy为相对于用例文件的路径，model为空时使用内置模型
+//
+//	model: model.conf
+//	policy: policy.csv
+//	domain: false
+//	cases:
+//	  - name: 读者可以查看账户
+//	    sub: alice
+//	    obj: /api/v1/accounts/:id
+//	    act: GET
+//	    allow: true
+type PolicySuite struct {
+	Model  string       `yaml:"model"`
+	Policy string       `yaml:"policy"`
+	Domain bool         `yaml:"domain"` // 使用多租户模型，用例需要填写dom
+	Cases  []PolicyCase `yaml:"cases"`
+}
+
+// PolicyCase 一条期望：(sub, [dom,] obj, act) 应当放行或拒绝
+type PolicyCase struct {
+	Name  string `yaml:"name"`
+	Sub   string `yaml:"sub"`
+	Dom   string `yaml:"dom,omitempty"`
+	Obj   string `yaml:"obj"`
+	Act   string `yaml:"act"`
+	Allow bool   `yaml:"allow"`
+}
+
+// PolicyCaseResult 一条用例的执行结果
+type PolicyCaseResult struct {
+	Case   PolicyCase
+	Got    bool
+	Policy []string // 命中的策略
+	Err    error
+}
+
+// Passed 结果与期望一致且没有出错
+func (r PolicyCaseResult) Passed() bool {
+	return r.Err == nil && r.Got == r.Case.Allow
+}
+
+// PolicySuiteResult 一个用例文件的执行结果
+type PolicySuiteResult struct {
+	File    string
+	Results []PolicyCaseResult
+}
+
+// Failures 未通过的用例
+func (r *PolicySuiteResult) Failures() []PolicyCaseResult {
+	failures := make([]PolicyCaseResult, 0)
+	for _, result := range r.Results {
+		if !result.Passed() {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// Passed 全部用例通过
+func (r *PolicySuiteResult) Passed() bool {
+	return len(r.Failures()) == 0
+}
+
+// Diff 可读的失败报告：每个失败用例的请求、期望、实际结果和命中的策略
+func (r *PolicySuiteResult) Diff() string {
+	failures := r.Failures()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d/%d 个用例失败\n", r.File, len(failures), len(r.Results))
+	for _, f := range failures {
+		fmt.Fprintf(&b, "--- FAIL %s\n", f.Case.Name)
+		fmt.Fprintf(&b, "    请求: (%s)\n", strings.Join(f.Case.requestValues(), ", "))
+		fmt.Fprintf(&b, "    期望: %s\n", effectName(f.Case.Allow))
+		if f.Err != nil {
+			fmt.Fprintf(&b, "    错误: %v\n", f.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "    实际: %s", effectName(f.Got))
+		if len(f.Policy) > 0 {
+			fmt.Fprintf(&b, "，命中策略 [%s]", strings.Join(f.Policy, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func effectName(allow bool) string {
+	if allow {
+		return "allow"
+	}
+	return "deny"
+}
+
+// requestValues 用例的请求参数，与模型的请求定义一致
+func (pc PolicyCase) requestValues() []string {
+	if pc.Dom != "" {
+		return []string{pc.Sub, pc.Dom, pc.Obj, pc.Act}
+	}
+	return []string{pc.Sub, pc.Obj, pc.Act}
+}
+
+// LoadPolicySuite 读取用例文件
+func LoadPolicySuite(file string) (*PolicySuite, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var suite PolicySuite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("解析用例文件 %s 失败: %w", file, err)
+	}
+	if suite.Policy == "" {
+		return nil, fmt.Errorf("用例文件 %s 缺少policy", file)
+	}
+	return &suite, nil
+}
+
+// RunPolicySuite 用用例文件中的模型和策略文件创建内存中的执行器，逐条执行用例，不需要数据库
+func RunPolicySuite(file string) (*PolicySuiteResult, error) {
+	suite, err := LoadPolicySuite(file)
+	if err != nil {
+		return nil, err
+	}
+	e, err := newSuiteEnforcer(file, suite)
+	if err != nil {
+		return nil, err
+	}
+	result := &PolicySuiteResult{File: file, Results: make([]PolicyCaseResult, 0, len(suite.Cases))}
+	for _, pc := range suite.Cases {
+		r := PolicyCaseResult{Case: pc}
+		if suite.Domain != (pc.Dom != "") {
+			r.Err = errors.New("启用多租户时dom必填，否则不能填写dom")
+		} else {
+			values := pc.requestValues()
+			rvals := make([]interface{}, len(values))
+			for i, v := range values {
+				rvals[i] = v
+			}
+			r.Got, r.Policy, r.Err = e.EnforceEx(rvals...)
+		}
+		result.Results = append(result.Results, r)
+	}
+	return result, nil
+}
+
+// newSuiteEnforcer 与InitCasbin使用相同的模型补充和自定义函数，策略从文件加载到内存
+func newSuiteEnforcer(file string, suite *PolicySuite) (*casbin.SyncedEnforcer, error) {
+	dir := filepath.Dir(file)
+	options := CasbinOptions{Driver: "file", EnableDomain: suite.Domain}
+	if suite.Model != "" {
+		options.ModelPath = suiteFilePath(dir, suite.Model)
+	}
+	m, err := newModel(options)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	e, err := casbin.NewSyncedEnforcer(m, fileadapter.NewAdapter(suiteFilePath(dir, suite.Policy)))
+	if err != nil {
+		return nil, fmt.Errorf("加载策略失败: %w", err)
+	}
+	c := &CasbinEnforcer{options: options, domainEnable: options.EnableDomain}
+	c.addFunctions(e)
+	return e, nil
+}
+
+// suiteFilePath 相对路径按用例文件所在目录解析
+func suiteFilePath(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// UpdatePolicySuite 按实际结果改写用例文件中的allow，保留注释和其他字段，返回改动的用例数
+func UpdatePolicySuite(file string) (int, error) {
+	result, err := RunPolicySuite(file)
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+	cases := mappingValue(doc.Content[0], "cases")
+	if cases == nil || cases.Kind != yaml.SequenceNode || len(cases.Content) != len(result.Results) {
+		return 0, fmt.Errorf("用例文件 %s 的cases格式不正确", file)
+	}
+	changed := 0
+	for i, r := range result.Results {
+		if r.Err != nil || r.Passed() {
+			continue
+		}
+		item := cases.Content[i]
+		value := mappingValue(item, "allow")
+		if value == nil {
+			value = &yaml.Node{Kind: yaml.ScalarNode}
+			item.Content = append(item.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "allow"}, value)
+		}
+		value.Tag = "!!bool"
+		value.Value = fmt.Sprint(r.Got)
+		changed++
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return 0, err
+	}
+	return changed, os.WriteFile(file, buf.Bytes(), 0o644)
+}
+
+// mappingValue 取映射节点中key对应的值
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// AssertPolicySuite go test中执行匹配pattern的全部用例文件，失败时输出差异
+func AssertPolicySuite(t testing.TB, pattern string) {
+	t.Helper()
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatalf("匹配用例文件失败: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("没有匹配 %s 的用例文件", pattern)
+	}
+	for _, file := range files {
+		result, err := RunPolicySuite(file)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if !result.Passed() {
+			t.Error("\n" + result.Diff())
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package casbin
+
+import (
+	"errors"
+	"fmt"
+	"go_casbin/internal/logger"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/fsnotify/fsnotify"
+)
+
+// modelReloadDelay 模型文件变更后等待写入完成再加载，编辑器保存时通常会连续触发多个事件
+const modelReloadDelay = 500 * time.Millisecond
+
+// roleCallPattern 匹配器中对角色定义的调用，如g(、g2(
+var roleCallPattern = regexp.MustCompile(`\b(g\d*)\(`)
+
+// addFunctions 注册匹配器使用的自定义函数，hasSubject绑定到e自身的角色管理器
+func (c *CasbinEnforcer) addFunctions(e casbin.IEnforcer) {
+	e.AddFunction("pathMatch", pathMatchFunc)
+	if c.abacEnable {
+		e.AddFunction("hasSubject", c.hasSubjectOf(e))
+	}
+}
+
+// ValidateModel 校验模型文件：语法、必需的定义、请求定义与鉴权参数一致、现有策略能全部加载，并用示例请求执行每个匹配器
+func (c *CasbinEnforcer) ValidateModel(modelPath string) error {
+	_, err := c.buildEnforcer(modelPath, c.enforcer().GetAdapter())
+	return err
+}
+
+// ReloadModel 加载新模型，校验通过且现有策略全部加载成功后原子替换执行器；失败时继续使用原执行器
+// 构建和校验新执行器时不持有原执行器的锁，鉴权和策略修改照常进行；替换前再加锁，补上构建期间的策略变更
+func (c *CasbinEnforcer) ReloadModel(modelPath string) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	if c.tenants != nil {
+		// 加载期间不加载或移除域，避免变更落在被替换的执行器上
+		c.tenants.loadMu.Lock()
+		defer c.tenants.loadMu.Unlock()
+	}
+
+	old := c.enforcer()
+	e, err := c.buildEnforcer(modelPath, old.GetAdapter())
+	if err != nil {
+		logger.ErrorWithErr("Casbin模型校验失败，继续使用当前模型", err, logger.String("modelPath", modelPath))
+		return err
+	}
+
+	// 替换前等待进行中的修改完成并阻止新的修改，新执行器补上构建期间的变更后再替换
+	c.swapMu.Lock()
+	defer c.swapMu.Unlock()
+	lock := old.GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+	rolesChanged, err := replayChanges(old.GetModel(), e.GetModel())
+	if err == nil && rolesChanged {
+		err = e.BuildRoleLinks()
+	}
+	if err != nil {
+		logger.ErrorWithErr("同步加载期间的策略变更失败，继续使用当前模型", err, logger.String("modelPath", modelPath))
+		return err
+	}
+	if c.watcher != nil {
+		if err := e.SetWatcher(c.watcher); err != nil {
+			logger.ErrorWithErr("设置Casbin watcher失败", err)
+			return err
+		}
+		if err := c.watcher.SetUpdateCallback(c.onPolicyMessage); err != nil {
+			return err
+		}
+	}
+	c.active.Store(e)
+	c.options.ModelPath = modelPath
+	c.invalidateAll()
+	logger.Info("Casbin模型已重新加载", logger.String("modelPath", modelPath))
+	return nil
+}
+
+// replayChanges 以原执行器的策略为准修正新执行器：构建期间本实例的修改和watcher同步的修改只落在原执行器上
+// 只处理两个模型都有的策略类型，返回g策略是否变化，变化时需要重建角色关系
+func replayChanges(from, to model.Model) (bool, error) {
+	rolesChanged := false
+	for _, sec := range []string{"p", "g"} {
+		for ptype := range to[sec] {
+			if _, ok := from[sec][ptype]; !ok {
+				continue
+			}
+			added, err := missingRules(from, to, sec, ptype)
+			if err != nil {
+				return false, err
+			}
+			removed, err := missingRules(to, from, sec, ptype)
+			if err != nil {
+				return false, err
+			}
+			if len(removed) > 0 {
+				if _, err := to.RemovePolicies(sec, ptype, removed); err != nil {
+					return false, err
+				}
+			}
+			if len(added) > 0 {
+				if err := to.AddPolicies(sec, ptype, added); err != nil {
+					return false, err
+				}
+			}
+			if sec == "g" && len(added)+len(removed) > 0 {
+				rolesChanged = true
+			}
+		}
+	}
+	return rolesChanged, nil
+}
+
+// missingRules from中有而to中没有的规则，返回副本
+func missingRules(from, to model.Model, sec, ptype string) ([][]string, error) {
+	var missing [][]string
+	for _, rule := range from[sec][ptype].Policy {
+		ok, err := to.HasPolicy(sec, ptype, rule)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			missing = append(missing, append([]string(nil), rule...))
+		}
+	}
+	return missing, nil
+}
+
+// WatchModel 监听模型文件，变更后自动ReloadModel，返回停止函数
+// 监听所在目录而不是文件本身，兼容编辑器先写临时文件再重命名的保存方式
+func (c *CasbinEnforcer) WatchModel(modelPath string) (stop func(), err error) {
+	if modelPath == "" {
+		return nil, errors.New("未配置模型文件，使用内置模型时不支持热加载")
+	}
+	absPath, err := resolveModelPath(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(absPath)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case <-done:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != absPath || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(modelReloadDelay, func() {
+					_ = c.ReloadModel(modelPath)
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.ErrorWithErr("监听Casbin模型文件失败", err, logger.String("modelPath", absPath))
+			}
+		}
+	}()
+	logger.Info("Casbin模型热加载已启用", logger.String("modelPath", absPath))
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			watcher.Close()
+		})
+	}, nil
+}
+
+// buildEnforcer 用新模型和现有策略创建执行器，不影响当前执行器
+func (c *CasbinEnforcer) buildEnforcer(modelPath string, adapter persist.Adapter) (*casbin.SyncedEnforcer, error) {
+	options := c.options
+	options.ModelPath = modelPath
+	m, err := newModel(options)
+	if err != nil {
+		return nil, fmt.Errorf("模型语法错误: %w", err)
+	}
+	if err := checkModel(m, options); err != nil {
+		return nil, err
+	}
+	e, err := casbin.NewSyncedEnforcer(m, adapter)
+	if err == nil && c.tenants != nil {
+		// 存储处于按域过滤状态时创建执行器不会加载策略，按已加载的域重新加载
+		err = e.LoadFilteredPolicy(c.tenants.filter())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("现有策略无法按新模型加载: %w", err)
+	}
+	c.addFunctions(e)
+	if err := c.enforceSamples(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// checkModel 检查必需的定义、匹配器引用的角色定义，以及请求定义与CasbinAuth传入的参数个数一致
+func checkModel(m model.Model, options CasbinOptions) error {
+	for _, sec := range []string{"r", "p", "e", "m"} {
+		if _, ok := m[sec][sec]; !ok {
+			return fmt.Errorf("模型缺少 %s 定义", sec)
+		}
+	}
+	for key, assertion := range m["m"] {
+		for _, match := range roleCallPattern.FindAllStringSubmatch(assertion.Value, -1) {
+			if _, ok := m["g"][match[1]]; !ok {
+				return fmt.Errorf("匹配器 %s 使用了未定义的角色 %s", key, match[1])
+			}
+		}
+	}
+	if err := checkStrategy(m, options.Strategy); err != nil {
+		return err
+	}
+	want := 3
+	if options.EnableDomain {
+		want = 4
+	}
+	if got := len(m["r"]["r"].Tokens); got != want {
+		return fmt.Errorf("请求定义 r 有 %d 个参数，当前配置需要 %d 个(sub, [dom,] obj, act)", got, want)
+	}
+	return nil
+}
+
+// enforceSamples 对每组请求定义执行一次示例请求，编译匹配器并对现有策略求值
+func (c *CasbinEnforcer) enforceSamples(e *casbin.SyncedEnforcer) error {
+	m := e.GetModel()
+	keys := make([]string, 0, len(m["r"]))
+	for key := range m["r"] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		suffix := strings.TrimPrefix(key, "r")
+		rvals := make([]interface{}, 0, len(m["r"][key].Tokens)+1)
+		if suffix != "" {
+			rvals = append(rvals, casbin.NewEnforceContext(suffix))
+		}
+		if key == "r2" && c.abacEnable {
+			rvals = append(rvals, Subject{}, Resource{}, "GET", Environment{})
+		} else {
+			for range m["r"][key].Tokens {
+				rvals = append(rvals, "sample")
+			}
+		}
+		if _, err := e.Enforce(rvals...); err != nil {
+			return fmt.Errorf("匹配器 m%s 执行失败: %w", suffix, err)
+		}
+	}
+	return nil
+}
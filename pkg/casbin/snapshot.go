@@ -0,0 +1,213 @@
+package casbin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_casbin/internal/logger"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// PolicySnapshot 策略快照，保存某一时刻的全部策略和角色继承规则
+type PolicySnapshot struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Operator  string         `gorm:"size:100" json:"operator"` // 操作人
+	Comment   string         `gorm:"size:255" json:"comment"`  // 备注
+	RuleCount int            `json:"rule_count"`               // 规则数
+	Rules     datatypes.JSON `json:"rules,omitempty"`          // 全部规则，[]PolicyLine
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (PolicySnapshot) TableName() string {
+	return "casbin_policy_snapshot"
+}
+
+// SnapshotDiff 两个快照之间的规则差异
+type SnapshotDiff struct {
+	From    uint         `json:"from"`    // 起始快照ID
+	To      uint         `json:"to"`      // 目标快照ID，0表示当前策略
+	Added   []PolicyLine `json:"added"`   // 目标中新增的规则
+	Removed []PolicyLine `json:"removed"` // 目标中删除的规则
+}
+
+var errSnapshotDisabled = errors.New("未启用策略快照")
+
+// EnableSnapshots 启用策略快照，数据库模式下快照与策略保存在同一个库，文件模式下保存在db中
+func (c *CasbinEnforcer) EnableSnapshots(db *gorm.DB) error {
+	if c.adapter != nil {
+		db = c.adapter.DB()
+	}
+	if db == nil {
+		return errors.New("策略快照需要数据库连接")
+	}
+	if err := db.AutoMigrate(&PolicySnapshot{}); err != nil {
+		logger.ErrorWithErr("初始化策略快照表失败", err)
+		return err
+	}
+	c.snapshotDB = db
+	return nil
+}
+
+// CreateSnapshot 保存当前全部策略为快照
+func (c *CasbinEnforcer) CreateSnapshot(operator, comment string) (*PolicySnapshot, error) {
+	if c.snapshotDB == nil {
+		return nil, errSnapshotDisabled
+	}
+	lines, err := c.ExportPolicies()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := json.Marshal(lines)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &PolicySnapshot{
+		Operator:  operator,
+		Comment:   comment,
+		RuleCount: len(lines),
+		Rules:     rules,
+	}
+	if err := c.snapshotDB.Create(snapshot).Error; err != nil {
+		logger.ErrorWithErr("保存策略快照失败", err, logger.String("operator", operator))
+		return nil, err
+	}
+	logger.Info("已保存策略快照", logger.Field("id", snapshot.ID), logger.String("operator", operator), logger.Int("rules", len(lines)))
+	return snapshot, nil
+}
+
+// ListSnapshots 分页查询快照，不返回规则内容
+func (c *CasbinEnforcer) ListSnapshots(page, pageSize int) ([]PolicySnapshot, int64, error) {
+	if c.snapshotDB == nil {
+		return nil, 0, errSnapshotDisabled
+	}
+	var total int64
+	if err := c.snapshotDB.Model(&PolicySnapshot{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	snapshots := make([]PolicySnapshot, 0)
+	err := c.snapshotDB.Omit("rules").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&snapshots).Error
+	return snapshots, total, err
+}
+
+// GetSnapshot 获取快照及其规则
+func (c *CasbinEnforcer) GetSnapshot(id uint) (*PolicySnapshot, error) {
+	if c.snapshotDB == nil {
+		return nil, errSnapshotDisabled
+	}
+	var snapshot PolicySnapshot
+	if err := c.snapshotDB.First(&snapshot, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("快照 %d 不存在", id)
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// snapshotLines 读取快照中的规则，id为0时返回当前策略
+func (c *CasbinEnforcer) snapshotLines(id uint) ([]PolicyLine, error) {
+	if id == 0 {
+		return c.ExportPolicies()
+	}
+	snapshot, err := c.GetSnapshot(id)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]PolicyLine, 0, snapshot.RuleCount)
+	if err := json.Unmarshal(snapshot.Rules, &lines); err != nil {
+		return nil, fmt.Errorf("解析快照 %d 失败: %w", id, err)
+	}
+	return lines, nil
+}
+
+// DiffSnapshots 比较两个快照，to为0时与当前策略比较
+func (c *CasbinEnforcer) DiffSnapshots(from, to uint) (*SnapshotDiff, error) {
+	fromLines, err := c.snapshotLines(from)
+	if err != nil {
+		return nil, err
+	}
+	toLines, err := c.snapshotLines(to)
+	if err != nil {
+		return nil, err
+	}
+	added, removed := diffPolicyLines(fromLines, toLines)
+	return &SnapshotDiff{From: from, To: to, Added: added, Removed: removed}, nil
+}
+
+// diffPolicyLines 计算从from到to新增和删除的规则
+func diffPolicyLines(from, to []PolicyLine) (added, removed []PolicyLine) {
+	key := func(line PolicyLine) string {
+		return line.Ptype + "\x1f" + strings.Join(line.Rule, "\x1f")
+	}
+	fromSet := make(map[string]struct{}, len(from))
+	for _, line := range from {
+		fromSet[key(line)] = struct{}{}
+	}
+	toSet := make(map[string]struct{}, len(to))
+	added = make([]PolicyLine, 0)
+	for _, line := range to {
+		toSet[key(line)] = struct{}{}
+		if _, ok := fromSet[key(line)]; !ok {
+			added = append(added, line)
+		}
+	}
+	removed = make([]PolicyLine, 0)
+	for _, line := range from {
+		if _, ok := toSet[key(line)]; !ok {
+			removed = append(removed, line)
+		}
+	}
+	return added, removed
+}
+
+// Rollback 将策略整体恢复到指定快照
+// 回滚前自动保存当前策略为快照，以便撤销回滚；数据库模式下整体替换在一个事务中完成，完成后通知其他实例重新加载
+func (c *CasbinEnforcer) Rollback(id uint, operator string) (*PolicySnapshot, error) {
+	lines, err := c.snapshotLines(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.ValidatePolicies(lines); err != nil {
+		return nil, fmt.Errorf("快照 %d 与当前模型不兼容: %w", id, err)
+	}
+	backup, err := c.CreateSnapshot(operator, fmt.Sprintf("回滚到快照 %d 前自动备份", id))
+	if err != nil {
+		return nil, err
+	}
+
+	lock := c.enforcer().GetLock()
+	lock.RLock()
+	m := c.enforcer().GetModel().Copy()
+	lock.RUnlock()
+	m.ClearPolicy()
+	for _, line := range lines {
+		if err := m.AddPolicy(line.Ptype[:1], line.Ptype, line.Rule); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.applyModel(m); err != nil {
+		logger.ErrorWithErr("回滚策略失败", err, logger.Field("snapshot", id))
+		return nil, err
+	}
+	logger.Info("策略已回滚", logger.Field("snapshot", id), logger.String("operator", operator), logger.Field("backup", backup.ID))
+	return backup, nil
+}
+
+// applyModel 用模型中的规则整体替换存储中的策略，重新加载并通知其他实例
+func (c *CasbinEnforcer) applyModel(m model.Model) error {
+	if err := c.saveModel(m); err != nil {
+		return err
+	}
+	if err := c.LoadPolicy(); err != nil {
+		return err
+	}
+	c.notifyWatcher()
+	return nil
+}
@@ -0,0 +1,292 @@
+package casbin
+
+import (
+	"errors"
+	"go_casbin/internal/logger"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// tenantLoader 按域加载策略：常驻的域启动时加载，按需加载的域首次请求时加载，空闲超时后从内存移除
+// 没有域字段的策略(如ABAC的p2)始终全部加载
+type tenantLoader struct {
+	mu       sync.Mutex
+	loadMu   sync.Mutex           // 串行加载，避免同一个域并发加载
+	resident map[string]bool      // 常驻的域，不会被移除
+	lastUsed map[string]time.Time // 已加载的域及最近使用时间
+	lazy     bool
+}
+
+func newTenantLoader(domains []string, lazy bool) *tenantLoader {
+	t := &tenantLoader{resident: make(map[string]bool), lastUsed: make(map[string]time.Time), lazy: lazy}
+	now := time.Now()
+	for _, domain := range domains {
+		t.resident[domain] = true
+		t.lastUsed[domain] = now
+	}
+	return t
+}
+
+// touch 已加载时更新最近使用时间并返回true
+func (t *tenantLoader) touch(domain string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.lastUsed[domain]; !ok {
+		return false
+	}
+	t.lastUsed[domain] = time.Now()
+	return true
+}
+
+func (t *tenantLoader) loaded(domain string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.lastUsed[domain]
+	return ok
+}
+
+// domains 已加载的全部域
+func (t *tenantLoader) domains() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	domains := make([]string, 0, len(t.lastUsed))
+	for domain := range t.lastUsed {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// filter 重新加载时使用的条件：已加载的域和没有域字段的策略
+func (t *tenantLoader) filter() *DomainFilter {
+	return &DomainFilter{Domains: t.domains(), Shared: true}
+}
+
+// idle 空闲超过idle的按需加载的域
+func (t *tenantLoader) idle(idle time.Duration, now time.Time) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	domains := make([]string, 0)
+	for domain, last := range t.lastUsed {
+		if !t.resident[domain] && now.Sub(last) >= idle {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// newTenantLoaderFor 校验按域加载的配置
+func newTenantLoaderFor(options CasbinOptions) (*tenantLoader, error) {
+	if len(options.Domains) == 0 && !options.LazyDomains {
+		return nil, nil
+	}
+	if !options.EnableDomain {
+		return nil, errors.New("按域加载策略需要启用多租户模型")
+	}
+	if options.Driver == "file" {
+		return nil, errors.New("按域加载策略需要使用数据库存储策略")
+	}
+	return newTenantLoader(options.Domains, options.LazyDomains), nil
+}
+
+// IsFiltered 是否只加载了部分域的策略
+func (c *CasbinEnforcer) IsFiltered() bool {
+	return c.tenants != nil
+}
+
+// DomainLoaded 域的策略是否已在内存中；加载全部策略时始终为true
+func (c *CasbinEnforcer) DomainLoaded(domain string) bool {
+	return c.tenants == nil || c.tenants.loaded(domain)
+}
+
+// LoadedDomains 已加载的域，加载全部策略时返回nil
+func (c *CasbinEnforcer) LoadedDomains() []string {
+	if c.tenants == nil {
+		return nil
+	}
+	return c.tenants.domains()
+}
+
+// ensureDomain 鉴权、修改或查询策略前确认域已加载：已加载时刷新使用时间，按需加载模式下首次使用时加载该域的策略
+// 未配置为常驻且未启用按需加载的域不会加载，其请求按没有策略处理；
+// 执行器按内存判断规则是否存在，删除这类域的策略应在加载了该域的实例上进行
+func (c *CasbinEnforcer) ensureDomain(domain string) {
+	t := c.tenants
+	if t == nil || domain == "" || t.touch(domain) || !t.lazy {
+		return
+	}
+	t.loadMu.Lock()
+	defer t.loadMu.Unlock()
+	if t.touch(domain) {
+		return
+	}
+	e := c.enforcer()
+	if err := e.LoadIncrementalFilteredPolicy(&DomainFilter{Domains: []string{domain}}); err != nil {
+		logger.ErrorWithErr("加载域策略失败", err, logger.String("domain", domain))
+		return
+	}
+	t.mu.Lock()
+	t.lastUsed[domain] = time.Now()
+	t.mu.Unlock()
+	// 未加载期间的变更消息已被忽略，失效该域规则涉及的主体，避免使用加载前的结果
+	lock := e.GetLock()
+	lock.RLock()
+	subjects := domainSubjects(e.GetModel(), domain)
+	lock.RUnlock()
+	c.invalidateSubjects(subjects)
+	logger.Info("已加载域策略", logger.String("domain", domain))
+}
+
+// domainSubjects 域内规则的主体：p规则的主体和g规则的用户
+// 域内的角色继承只由该域的g规则给出，加载或移除该域后鉴权结果可能变化的主体都在其中
+func domainSubjects(m model.Model, domain string) []string {
+	seen := make(map[string]bool)
+	subjects := make([]string, 0)
+	for _, sec := range []string{"p", "g"} {
+		for ptype, assertion := range m[sec] {
+			index := domainIndex(m, sec, ptype)
+			if index < 0 {
+				continue
+			}
+			for _, rule := range assertion.Policy {
+				if index < len(rule) && rule[index] == domain && !seen[rule[0]] {
+					seen[rule[0]] = true
+					subjects = append(subjects, rule[0])
+				}
+			}
+		}
+	}
+	return subjects
+}
+
+// ensureRuleDomains 修改或查询规则前加载规则所在的域：未加载的域在内存中没有规则，删除时找不到，添加的规则不会进入内存
+func (c *CasbinEnforcer) ensureRuleDomains(sec, ptype string, rules ...[]string) {
+	if c.tenants == nil {
+		return
+	}
+	index := domainIndex(c.enforcer().GetModel(), sec, ptype)
+	if index < 0 {
+		return
+	}
+	for _, rule := range rules {
+		if index < len(rule) {
+			c.ensureDomain(rule[index])
+		}
+	}
+}
+
+// ensureFilterDomain 按条件删除或查询规则时，条件中指定了域则先加载该域
+func (c *CasbinEnforcer) ensureFilterDomain(sec, ptype string, fieldIndex int, fieldValues []string) {
+	if c.tenants == nil {
+		return
+	}
+	index := domainIndex(c.enforcer().GetModel(), sec, ptype) - fieldIndex
+	if index >= 0 && index < len(fieldValues) {
+		c.ensureDomain(fieldValues[index])
+	}
+}
+
+// ensureRequestDomain 从请求参数(sub, dom, obj, act)中取出域并确认已加载
+func (c *CasbinEnforcer) ensureRequestDomain(rvals []interface{}) {
+	if c.tenants == nil {
+		return
+	}
+	if len(rvals) == 5 {
+		rvals = rvals[1:] // 第一个参数为EnforceContext
+	}
+	if len(rvals) != 4 {
+		return
+	}
+	if domain, ok := rvals[1].(string); ok {
+		c.ensureDomain(domain)
+	}
+}
+
+// EvictDomain 从内存中移除域的策略，不影响存储；常驻的域不能移除
+func (c *CasbinEnforcer) EvictDomain(domain string) error {
+	t := c.tenants
+	if t == nil {
+		return errors.New("未启用按域加载策略")
+	}
+	if t.resident[domain] {
+		return errors.New("常驻的域不能移除")
+	}
+	t.loadMu.Lock()
+	defer t.loadMu.Unlock()
+	if !t.loaded(domain) {
+		return nil
+	}
+
+	e := c.enforcer()
+	lock := e.GetLock()
+	lock.Lock()
+	m := e.GetModel()
+	subjects := domainSubjects(m, domain)
+	var err error
+	for _, sec := range []string{"p", "g"} {
+		for ptype := range m[sec] {
+			index := domainIndex(m, sec, ptype)
+			if index < 0 {
+				continue
+			}
+			if _, _, err = m.RemoveFilteredPolicy(sec, ptype, index, domain); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = e.Enforcer.BuildRoleLinks()
+	}
+	lock.Unlock()
+	if err != nil {
+		logger.ErrorWithErr("移除域策略失败", err, logger.String("domain", domain))
+		return err
+	}
+
+	t.mu.Lock()
+	delete(t.lastUsed, domain)
+	t.mu.Unlock()
+	c.invalidateSubjects(subjects)
+	logger.Info("已移除空闲域的策略", logger.String("domain", domain))
+	return nil
+}
+
+// StartDomainEviction 后台定期移除空闲超过idle的按需加载的域
+func (c *CasbinEnforcer) StartDomainEviction(idle time.Duration) {
+	if c.tenants == nil || !c.tenants.lazy || idle <= 0 {
+		return
+	}
+	interval := idle / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			for _, domain := range c.tenants.idle(idle, now) {
+				_ = c.EvictDomain(domain)
+			}
+		}
+	}()
+	logger.Info("已启用空闲域策略移除", logger.Field("idle", idle))
+}
+
+// filterDomainRules 只保留已加载的域和没有域字段的规则，其他域的变更在加载时从存储读取
+func (c *CasbinEnforcer) filterDomainRules(m model.Model, sec, ptype string, rules [][]string) [][]string {
+	index := domainIndex(m, sec, ptype)
+	if c.tenants == nil || index < 0 {
+		return rules
+	}
+	kept := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		if index < len(rule) && c.tenants.loaded(rule[index]) {
+			kept = append(kept, rule)
+		}
+	}
+	return kept
+}
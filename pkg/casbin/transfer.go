@@ -0,0 +1,271 @@
+package casbin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_casbin/internal/logger"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Format 策略导入导出格式
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// ParseFormat 解析导入导出格式，为空时使用csv
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "csv":
+		return FormatCSV, nil
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("不支持的策略格式: %s", s)
+	}
+}
+
+// ContentType 导出文件的MIME类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJSON:
+		return "application/json"
+	case FormatYAML:
+		return "application/yaml"
+	default:
+		return "text/csv"
+	}
+}
+
+// ImportMode 策略导入模式
+type ImportMode string
+
+const (
+	ImportMerge   ImportMode = "merge"   // 合并：保留现有策略，只添加不存在的规则
+	ImportReplace ImportMode = "replace" // 替换：用导入的规则替换全部现有策略
+)
+
+// ParseImportMode 解析导入模式，为空时为合并
+func ParseImportMode(s string) (ImportMode, error) {
+	switch ImportMode(strings.ToLower(s)) {
+	case "", ImportMerge:
+		return ImportMerge, nil
+	case ImportReplace:
+		return ImportReplace, nil
+	default:
+		return "", fmt.Errorf("不支持的导入模式: %s", s)
+	}
+}
+
+// PolicyLine 一条策略或角色继承规则，与policy.csv中的一行对应
+type PolicyLine struct {
+	Ptype string   `json:"ptype" yaml:"ptype"` // p、g、g2...
+	Rule  []string `json:"rule" yaml:"rule"`   // 规则字段
+}
+
+// ImportResult 策略导入结果
+type ImportResult struct {
+	Mode    ImportMode `json:"mode"`    // 导入模式
+	Total   int        `json:"total"`   // 导入的规则数
+	Added   int        `json:"added"`   // 新增的规则数
+	Skipped int        `json:"skipped"` // 已存在而跳过的规则数
+}
+
+// modelPolicies 按p、g及ptype排序列出模型中的全部规则
+func modelPolicies(m model.Model) []PolicyLine {
+	lines := make([]PolicyLine, 0)
+	for _, sec := range []string{"p", "g"} {
+		ptypes := make([]string, 0, len(m[sec]))
+		for ptype := range m[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+		for _, ptype := range ptypes {
+			for _, rule := range m[sec][ptype].Policy {
+				lines = append(lines, PolicyLine{Ptype: ptype, Rule: append([]string(nil), rule...)})
+			}
+		}
+	}
+	return lines
+}
+
+// ExportPolicies 导出全部策略和角色继承规则
+func (c *CasbinEnforcer) ExportPolicies() ([]PolicyLine, error) {
+	m, err := c.storedModel()
+	if err != nil {
+		return nil, err
+	}
+	return modelPolicies(m), nil
+}
+
+// storedModel 包含存储中全部策略的模型副本；按域加载时内存中只有部分域，从存储重新读取
+func (c *CasbinEnforcer) storedModel() (model.Model, error) {
+	lock := c.enforcer().GetLock()
+	lock.RLock()
+	m := c.enforcer().GetModel().Copy()
+	lock.RUnlock()
+	if c.adapter == nil || !c.adapter.IsFiltered() {
+		return m, nil
+	}
+	m.ClearPolicy()
+	if err := c.adapter.loadLines(c.adapter.DB(), m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EncodePolicies 将规则编码为指定格式
+func EncodePolicies(format Format, lines []PolicyLine) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(lines, "", "  ")
+	case FormatYAML:
+		return yaml.Marshal(lines)
+	case FormatCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		for _, line := range lines {
+			if err := w.Write(append([]string{line.Ptype}, line.Rule...)); err != nil {
+				return nil, err
+			}
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	default:
+		return nil, fmt.Errorf("不支持的策略格式: %s", format)
+	}
+}
+
+// DecodePolicies 解析指定格式的规则，csv格式与policy.csv相同
+func DecodePolicies(format Format, data []byte) ([]PolicyLine, error) {
+	lines := make([]PolicyLine, 0)
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal(data, &lines); err != nil {
+			return nil, fmt.Errorf("解析json策略失败: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &lines); err != nil {
+			return nil, fmt.Errorf("解析yaml策略失败: %w", err)
+		}
+	case FormatCSV:
+		r := csv.NewReader(bytes.NewReader(data))
+		r.Comment = '#'
+		r.TrimLeadingSpace = true
+		r.FieldsPerRecord = -1
+		for {
+			record, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("解析csv策略失败: %w", err)
+			}
+			if len(record) == 0 || (len(record) == 1 && strings.TrimSpace(record[0]) == "") {
+				continue
+			}
+			for i := range record {
+				record[i] = strings.TrimSpace(record[i])
+			}
+			lines = append(lines, PolicyLine{Ptype: record[0], Rule: record[1:]})
+		}
+	default:
+		return nil, fmt.Errorf("不支持的策略格式: %s", format)
+	}
+	return lines, nil
+}
+
+// ValidatePolicies 按当前模型校验规则：ptype必须在模型中定义，字段数与定义一致且不能为空
+func (c *CasbinEnforcer) ValidatePolicies(lines []PolicyLine) error {
+	m := c.enforcer().GetModel()
+	for i, line := range lines {
+		if line.Ptype == "" {
+			return fmt.Errorf("第%d条规则缺少ptype", i+1)
+		}
+		sec := line.Ptype[:1]
+		ast, err := m.GetAssertion(sec, line.Ptype)
+		if err != nil || (sec != "p" && sec != "g") {
+			return fmt.Errorf("第%d条规则的ptype %s 未在模型中定义", i+1, line.Ptype)
+		}
+		expected := len(ast.Tokens)
+		if sec == "g" {
+			expected = strings.Count(ast.Value, "_")
+		}
+		if len(line.Rule) != expected {
+			return fmt.Errorf("第%d条规则字段数为%d，模型 %s 要求%d个", i+1, len(line.Rule), line.Ptype, expected)
+		}
+		for _, field := range line.Rule {
+			if field == "" {
+				return fmt.Errorf("第%d条规则包含空字段", i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// ImportPolicies 校验并导入规则
+// 先在模型副本上合并或替换，再整体写入存储(数据库模式在一个事务中完成)，成功后重新加载并通知其他实例
+func (c *CasbinEnforcer) ImportPolicies(lines []PolicyLine, mode ImportMode) (*ImportResult, error) {
+	if err := c.ValidatePolicies(lines); err != nil {
+		return nil, err
+	}
+
+	m, err := c.storedModel()
+	if err != nil {
+		return nil, err
+	}
+	if mode == ImportReplace {
+		m.ClearPolicy()
+	}
+	result := &ImportResult{Mode: mode, Total: len(lines)}
+	for _, line := range lines {
+		sec := line.Ptype[:1]
+		exists, err := m.HasPolicy(sec, line.Ptype, line.Rule)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			result.Skipped++
+			continue
+		}
+		if err := m.AddPolicy(sec, line.Ptype, line.Rule); err != nil {
+			return nil, err
+		}
+		result.Added++
+	}
+	if mode == ImportMerge && result.Added == 0 {
+		return result, nil
+	}
+
+	if err := c.applyModel(m); err != nil {
+		logger.ErrorWithErr("写入导入的策略失败", err, logger.String("mode", string(mode)))
+		return nil, err
+	}
+	logger.Info("策略导入完成", logger.String("mode", string(mode)), logger.Int("total", result.Total), logger.Int("added", result.Added))
+	return result, nil
+}
+
+// saveModel 将模型中的全部规则写入存储，数据库模式在事务中执行
+func (c *CasbinEnforcer) saveModel(m model.Model) error {
+	if c.adapter != nil {
+		return c.adapter.SavePolicy(m)
+	}
+	adapter := c.enforcer().GetAdapter()
+	if adapter == nil {
+		return errors.New("未配置策略存储")
+	}
+	return adapter.SavePolicy(m)
+}
@@ -0,0 +1,296 @@
+package casbin
+
+import (
+	"encoding/json"
+	"fmt"
+	"go_casbin/internal/logger"
+	"go_casbin/pkg/etcd"
+	"go_casbin/pkg/redis"
+	"go_casbin/pkg/util"
+	"sync"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// UpdateType 策略变更类型
+type UpdateType string
+
+const (
+	Update                        UpdateType = "Update" // 全量变更，接收方重新LoadPolicy
+	UpdateForAddPolicy            UpdateType = "UpdateForAddPolicy"
+	UpdateForRemovePolicy         UpdateType = "UpdateForRemovePolicy"
+	UpdateForRemoveFilteredPolicy UpdateType = "UpdateForRemoveFilteredPolicy"
+	UpdateForSavePolicy           UpdateType = "UpdateForSavePolicy"
+	UpdateForAddPolicies          UpdateType = "UpdateForAddPolicies"
+	UpdateForRemovePolicies       UpdateType = "UpdateForRemovePolicies"
+	UpdateForUpdatePolicy         UpdateType = "UpdateForUpdatePolicy"
+	UpdateForUpdatePolicies       UpdateType = "UpdateForUpdatePolicies"
+)
+
+// PolicyMessage 实例间广播的策略变更消息
+type PolicyMessage struct {
+	Method      UpdateType `json:"method"`                 // 变更类型
+	ID          string     `json:"id"`                     // 发送方实例ID，用于忽略自己发出的消息
+	Sec         string     `json:"sec,omitempty"`          // p 或 g
+	Ptype       string     `json:"ptype,omitempty"`        // p、g、g2...
+	OldRules    [][]string `json:"old_rules,omitempty"`    // 更新前的规则
+	NewRules    [][]string `json:"new_rules,omitempty"`    // 新增/删除/更新后的规则
+	FieldIndex  int        `json:"field_index,omitempty"`  // 按条件删除的起始字段
+	FieldValues []string   `json:"field_values,omitempty"` // 按条件删除的字段值
+}
+
+// MessageWatcher 基于消息广播的watcher，传输层(etcd、redis)只需提供publish并在收到消息时调用Receive
+type MessageWatcher struct {
+	instanceID string
+	publish    func(payload string) error
+	close      func()
+
+	mu       sync.RWMutex
+	callback func(string)
+}
+
+var (
+	_ persist.WatcherEx        = (*MessageWatcher)(nil)
+	_ persist.UpdatableWatcher = (*MessageWatcher)(nil)
+)
+
+// NewMessageWatcher 创建基于消息广播的watcher，publish负责把消息发给其他实例，close在Close时调用
+func NewMessageWatcher(publish func(payload string) error, close func()) *MessageWatcher {
+	return &MessageWatcher{
+		instanceID: util.RandomUUID(),
+		publish:    publish,
+		close:      close,
+	}
+}
+
+// SetUpdateCallback 设置收到其他实例变更时的回调
+func (w *MessageWatcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+// Receive 传输层收到消息时调用，忽略本实例发出的消息；无法解析的消息按全量变更处理
+func (w *MessageWatcher) Receive(payload string) {
+	if payload == "" {
+		return
+	}
+	var msg PolicyMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		logger.ErrorWithErr("解析策略变更消息失败，按全量变更处理", err, logger.String("payload", payload))
+		full, _ := json.Marshal(&PolicyMessage{Method: Update})
+		payload = string(full)
+	} else if msg.ID == w.instanceID {
+		return
+	}
+	w.mu.RLock()
+	callback := w.callback
+	w.mu.RUnlock()
+	if callback != nil {
+		callback(payload)
+	}
+}
+
+func (w *MessageWatcher) send(msg *PolicyMessage) error {
+	msg.ID = w.instanceID
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := w.publish(string(payload)); err != nil {
+		logger.ErrorWithErr("广播策略变更失败", err, logger.String("method", string(msg.Method)))
+		return err
+	}
+	return nil
+}
+
+// Update 通知其他实例全量重新加载策略
+func (w *MessageWatcher) Update() error {
+	return w.send(&PolicyMessage{Method: Update})
+}
+
+// Close 停止监听
+func (w *MessageWatcher) Close() {
+	if w.close != nil {
+		w.close()
+	}
+}
+
+func (w *MessageWatcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.send(&PolicyMessage{Method: UpdateForAddPolicy, Sec: sec, Ptype: ptype, NewRules: [][]string{params}})
+}
+
+func (w *MessageWatcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.send(&PolicyMessage{Method: UpdateForRemovePolicy, Sec: sec, Ptype: ptype, NewRules: [][]string{params}})
+}
+
+func (w *MessageWatcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.send(&PolicyMessage{Method: UpdateForRemoveFilteredPolicy, Sec: sec, Ptype: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues})
+}
+
+func (w *MessageWatcher) UpdateForSavePolicy(model model.Model) error {
+	return w.send(&PolicyMessage{Method: UpdateForSavePolicy})
+}
+
+func (w *MessageWatcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.send(&PolicyMessage{Method: UpdateForAddPolicies, Sec: sec, Ptype: ptype, NewRules: rules})
+}
+
+func (w *MessageWatcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.send(&PolicyMessage{Method: UpdateForRemovePolicies, Sec: sec, Ptype: ptype, NewRules: rules})
+}
+
+func (w *MessageWatcher) UpdateForUpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return w.send(&PolicyMessage{Method: UpdateForUpdatePolicy, Sec: sec, Ptype: ptype, OldRules: [][]string{oldRule}, NewRules: [][]string{newRule}})
+}
+
+func (w *MessageWatcher) UpdateForUpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return w.send(&PolicyMessage{Method: UpdateForUpdatePolicies, Sec: sec, Ptype: ptype, OldRules: oldRules, NewRules: newRules})
+}
+
+// InitWatcher 根据配置初始化策略同步watcher，driver为空时不启用
+func InitWatcher(driver, key string) error {
+	var watcher persist.Watcher
+	switch driver {
+	case "":
+		return nil
+	case "etcd":
+		watcher = NewEtcdWatcher(etcd.GetEtcdInstance(), key)
+	case "redis":
+		client := redis.GetRedisInstance()
+		redisWatcher, err := NewRedisWatcher(&client, key)
+		if err != nil {
+			logger.ErrorWithErr("订阅策略变更频道失败", err, logger.String("channel", key))
+			return err
+		}
+		watcher = redisWatcher
+	default:
+		return fmt.Errorf("不支持的watcher类型: %s", driver)
+	}
+	if err := CasbinService.SetWatcher(watcher); err != nil {
+		return err
+	}
+	logger.Info("Casbin watcher初始化成功", logger.String("driver", driver))
+	return nil
+}
+
+// SetWatcher 设置策略同步watcher，收到其他实例的变更后增量应用到本地
+func (c *CasbinEnforcer) SetWatcher(watcher persist.Watcher) error {
+	if err := c.enforcer().SetWatcher(watcher); err != nil {
+		logger.ErrorWithErr("设置Casbin watcher失败", err)
+		return err
+	}
+	c.watcher = watcher
+	return watcher.SetUpdateCallback(c.onPolicyMessage)
+}
+
+// notifyWatcher 绕过执行器直接写入存储后，通知其他实例全量重新加载
+func (c *CasbinEnforcer) notifyWatcher() {
+	if c.watcher == nil {
+		return
+	}
+	if err := c.watcher.Update(); err != nil {
+		logger.ErrorWithErr("通知其他实例重新加载策略失败", err)
+	}
+}
+
+// onPolicyMessage 处理其他实例广播的策略变更
+func (c *CasbinEnforcer) onPolicyMessage(payload string) {
+	var msg PolicyMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		logger.ErrorWithErr("解析策略变更消息失败，重新加载全部策略", err, logger.String("payload", payload))
+		_ = c.LoadPolicy()
+		return
+	}
+	if err := c.applyPolicyMessage(&msg); err != nil {
+		// 增量应用失败时回退为全量加载，保证最终一致
+		logger.ErrorWithErr("增量同步策略失败，重新加载全部策略", err, logger.String("method", string(msg.Method)))
+		_ = c.LoadPolicy()
+		return
+	}
+	switch msg.Method {
+	case UpdateForAddPolicy, UpdateForAddPolicies, UpdateForRemovePolicy, UpdateForRemovePolicies:
+		c.invalidateRules(msg.Sec, msg.NewRules...)
+	case UpdateForUpdatePolicy, UpdateForUpdatePolicies:
+		c.invalidateRules(msg.Sec, append(msg.OldRules, msg.NewRules...)...)
+	default:
+		c.invalidateAll()
+	}
+	logger.Info("已同步其他实例的策略变更", logger.String("method", string(msg.Method)), logger.String("from", msg.ID))
+}
+
+// applyPolicyMessage 仅修改内存中的模型，不写回adapter，也不再次广播
+func (c *CasbinEnforcer) applyPolicyMessage(msg *PolicyMessage) error {
+	switch msg.Method {
+	case UpdateForAddPolicy, UpdateForAddPolicies,
+		UpdateForRemovePolicy, UpdateForRemovePolicies,
+		UpdateForRemoveFilteredPolicy,
+		UpdateForUpdatePolicy, UpdateForUpdatePolicies:
+	default:
+		return c.LoadPolicy()
+	}
+
+	c.swapMu.RLock()
+	defer c.swapMu.RUnlock()
+	lock := c.enforcer().GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	m := c.enforcer().GetModel()
+	// 按域加载时忽略未加载的域的规则，这些域加载时会从存储读取最新策略
+	msg.NewRules = c.filterDomainRules(m, msg.Sec, msg.Ptype, msg.NewRules)
+	msg.OldRules = c.filterDomainRules(m, msg.Sec, msg.Ptype, msg.OldRules)
+	switch msg.Method {
+	case UpdateForAddPolicy, UpdateForAddPolicies:
+		affected, err := m.AddPoliciesWithAffected(msg.Sec, msg.Ptype, msg.NewRules)
+		if err != nil {
+			return err
+		}
+		return c.rebuildRoleLinks(model.PolicyAdd, msg.Sec, msg.Ptype, affected)
+	case UpdateForRemovePolicy, UpdateForRemovePolicies:
+		affected, err := m.RemovePoliciesWithAffected(msg.Sec, msg.Ptype, msg.NewRules)
+		if err != nil {
+			return err
+		}
+		return c.rebuildRoleLinks(model.PolicyRemove, msg.Sec, msg.Ptype, affected)
+	case UpdateForRemoveFilteredPolicy:
+		_, affected, err := m.RemoveFilteredPolicy(msg.Sec, msg.Ptype, msg.FieldIndex, msg.FieldValues...)
+		if err != nil {
+			return err
+		}
+		return c.rebuildRoleLinks(model.PolicyRemove, msg.Sec, msg.Ptype, affected)
+	default:
+		if len(msg.OldRules) != len(msg.NewRules) {
+			// 过滤后更新前后的规则不再一一对应，按删除旧规则、添加新规则处理
+			removed, err := m.RemovePoliciesWithAffected(msg.Sec, msg.Ptype, msg.OldRules)
+			if err != nil {
+				return err
+			}
+			if err := c.rebuildRoleLinks(model.PolicyRemove, msg.Sec, msg.Ptype, removed); err != nil {
+				return err
+			}
+			added, err := m.AddPoliciesWithAffected(msg.Sec, msg.Ptype, msg.NewRules)
+			if err != nil {
+				return err
+			}
+			return c.rebuildRoleLinks(model.PolicyAdd, msg.Sec, msg.Ptype, added)
+		}
+		if _, err := m.UpdatePolicies(msg.Sec, msg.Ptype, msg.OldRules, msg.NewRules); err != nil {
+			return err
+		}
+		if err := c.rebuildRoleLinks(model.PolicyRemove, msg.Sec, msg.Ptype, msg.OldRules); err != nil {
+			return err
+		}
+		return c.rebuildRoleLinks(model.PolicyAdd, msg.Sec, msg.Ptype, msg.NewRules)
+	}
+}
+
+// rebuildRoleLinks g策略变更后增量更新角色继承关系
+func (c *CasbinEnforcer) rebuildRoleLinks(op model.PolicyOp, sec, ptype string, rules [][]string) error {
+	if sec != "g" || len(rules) == 0 {
+		return nil
+	}
+	return c.enforcer().Enforcer.BuildIncrementalRoleLinks(op, ptype, rules)
+}
@@ -0,0 +1,24 @@
+package casbin
+
+import (
+	"context"
+	"go_casbin/pkg/etcd"
+
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// DefaultEtcdWatcherKey 默认的策略变更广播key
+const DefaultEtcdWatcherKey = "/casbin/policy/update"
+
+// NewEtcdWatcher 基于etcd的策略同步watcher：变更写入key，所有实例监听该key
+func NewEtcdWatcher(client *etcd.EtcdServiceImpl, key string) persist.WatcherEx {
+	if key == "" {
+		key = DefaultEtcdWatcherKey
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewMessageWatcher(func(payload string) error {
+		return client.Put(context.Background(), key, payload)
+	}, cancel)
+	client.Watch(ctx, key, w.Receive)
+	return w
+}
@@ -0,0 +1,42 @@
+package casbin
+
+import (
+	"context"
+	"go_casbin/internal/logger"
+	"go_casbin/pkg/redis"
+
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// DefaultRedisWatcherChannel 默认的策略变更广播频道
+const DefaultRedisWatcherChannel = "casbin:policy:update"
+
+// NewRedisWatcher 基于redis发布订阅的策略同步watcher，适用于没有etcd的环境
+func NewRedisWatcher(client *redis.RedisServiceImpl, channel string) (persist.WatcherEx, error) {
+	if channel == "" {
+		channel = DefaultRedisWatcherChannel
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := client.Subscribe(ctx, channel)
+	// 等待订阅确认，确保连接可用
+	if _, err := pubsub.Receive(ctx); err != nil {
+		cancel()
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	w := NewMessageWatcher(func(payload string) error {
+		return client.Publish(context.Background(), channel, payload)
+	}, func() {
+		cancel()
+		if err := pubsub.Close(); err != nil {
+			logger.ErrorWithErr("关闭redis订阅失败", err, logger.String("channel", channel))
+		}
+	})
+	go func() {
+		for msg := range pubsub.Channel() {
+			w.Receive(msg.Payload)
+		}
+	}()
+	return w, nil
+}
@@ -3,16 +3,26 @@ package database
 import (
 	"fmt"
 	"go_casbin/internal/logger"
+	"go_casbin/pkg/path"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// 内嵌数据库类型，无需外部数据库服务
+const (
+	DriverSQLite = "sqlite" // 本地文件，DBName或DSN为文件路径
+	DriverMemory = "memory" // 进程内存，DBName或DSN为库名，进程退出后数据丢失
+)
+
 var (
 	db   *gorm.DB
-	once sync.Once
+	dbMu sync.RWMutex
 )
 
 type DBOption struct {
@@ -27,34 +37,53 @@ type DBOption struct {
 	Loc       *string ` json:"loc" `
 }
 
-// InitDB 初始化数据库连接
+// InitDB 初始化数据库连接；重复调用时替换全局连接并关闭原连接，测试中使用不同的库名即可得到独立的数据库
 func InitDB(option DBOption) error {
-	var err error
-	once.Do(func() {
-		db, err = connectDB(option)
-		if err != nil {
-			logger.ErrorWithErr("数据库连接失败", err)
-			return
-		}
+	conn, err := connectDB(option)
+	if err != nil {
+		logger.ErrorWithErr("数据库连接失败", err)
+		return err
+	}
 
-		// 设置连接池
-		sqlDB, _ := db.DB()
-		sqlDB.SetMaxIdleConns(10)
-		sqlDB.SetMaxOpenConns(100)
+	// 设置连接池
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxIdleConns(10)
+	if !IsEmbedded(option.DBType) {
+		sqlDB.SetMaxOpenConns(100) // 内嵌数据库由Open限制为单连接
+	}
 
-		logger.Info("数据库连接成功")
-	})
-	return err
+	dbMu.Lock()
+	previous := db
+	db = conn
+	dbMu.Unlock()
+	if previous != nil {
+		closeConn(previous)
+	}
+	logger.Info("数据库连接成功")
+	return nil
 }
 
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
+	dbMu.RLock()
+	defer dbMu.RUnlock()
 	if db == nil {
 		panic("数据库未初始化，请先调用 InitDB()")
 	}
 	return db
 }
 
+func closeConn(conn *gorm.DB) {
+	if sqlDB, err := conn.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logger.ErrorWithErr("关闭数据库连接失败", err)
+		}
+	}
+}
+
 // connectDB 连接数据库
 func connectDB(option DBOption) (*gorm.DB, error) {
 	cfg := option
@@ -79,13 +108,78 @@ func connectDB(option DBOption) (*gorm.DB, error) {
 			cfg.Host, cfg.Username, cfg.Password, cfg.DBName, cfg.Port, *cfg.Loc)
 		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
 
+	// 支持sqlite和内存数据库，用于本地开发和测试
+	case DriverSQLite, DriverMemory:
+		return Open(cfg.DBType, cfg.DBName)
+
 	default:
 		return nil, fmt.Errorf("不支持的数据库类型: %s", cfg.DBType)
 	}
 }
 
+// Open 使用原始DSN打开独立的数据库连接，供casbin等使用单独数据源的组件使用
+func Open(driver, dsn string) (*gorm.DB, error) {
+	switch driver {
+	case "mysql":
+		return gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	case "postgres":
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	case DriverSQLite, DriverMemory:
+		dsn, err := sqliteDSN(driver, dsn)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		// sqlite同一时间只允许一个写事务，共享缓存的内存库并发写入会直接报错而不是等待
+		sqlDB, err := conn.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		return conn, nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型: %s", driver)
+	}
+}
+
+// IsEmbedded 是否为sqlite或内存数据库，这类数据库启动时没有表结构，需要自动迁移
+func IsEmbedded(driver string) bool {
+	return driver == DriverSQLite || driver == DriverMemory
+}
+
+// sqliteDSN 生成sqlite连接串
+// sqlite: 相对路径按项目根目录解析，已是file:连接串时原样使用
+// memory: 同名的内存库在进程内共享，连接全部关闭后数据丢失；业务库和策略库应使用不同的库名
+func sqliteDSN(driver, dsn string) (string, error) {
+	if driver == DriverMemory {
+		if dsn == "" {
+			dsn = "go_casbin"
+		}
+		return fmt.Sprintf("file:%s?mode=memory&cache=shared&_busy_timeout=5000", dsn), nil
+	}
+	if dsn == "" {
+		return "", fmt.Errorf("sqlite数据库文件路径不能为空")
+	}
+	if strings.HasPrefix(dsn, "file:") {
+		return dsn, nil
+	}
+	if !filepath.IsAbs(dsn) {
+		absPath, err := path.GetAbsolutePath(dsn)
+		if err != nil {
+			return "", err
+		}
+		dsn = absPath
+	}
+	return dsn + "?_busy_timeout=5000", nil
+}
+
 // CloseDB 关闭数据库连接
 func CloseDB() error {
+	dbMu.RLock()
+	defer dbMu.RUnlock()
 	if db != nil {
 		sqlDB, err := db.DB()
 		if err != nil {
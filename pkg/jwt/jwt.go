@@ -26,6 +26,7 @@ type Account struct {
 	Platform   string `json:"platform,omitempty"`//平台
 	SystemId   string `json:"system_id,omitempty"`//系统id
 	TenantId   string `json:"tenant_id,omitempty"`//租户id
+	DepartmentId string `json:"department_id,omitempty"`//部门id
 	AppId      string `json:"app_id,omitempty"`//应用id
 	Status    int8 `json:"status,omitempty"`//状态
 	IsVerified bool `json:"is_verified,omitempty"`//是否验证
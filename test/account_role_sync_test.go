@@ -0,0 +1,180 @@
+package test
+
+import (
+	"context"
+	"go_casbin/internal/model"
+	"go_casbin/internal/service"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/database"
+	"testing"
+)
+
+// setupAccountRoles 业务库和策略库为本次测试独立的内存库
+func setupAccountRoles(t *testing.T) (*casbinService.CasbinEnforcer, model.Role, model.Role) {
+	t.Helper()
+	enforcer := newInstances(t, 1, casbinService.CasbinOptions{})[0]
+	if err := database.InitDB(database.DBOption{DBType: database.DriverMemory, DBName: testDBName(t) + "_business"}); err != nil {
+		t.Fatalf("初始化内存数据库失败: %v", err)
+	}
+	if err := database.AutoMigrate(&model.Account{}, &model.Role{}); err != nil {
+		t.Fatal(err)
+	}
+	reader, writer := model.Role{Name: "reader"}, model.Role{Name: "writer"}
+	for _, role := range []*model.Role{&reader, &writer} {
+		if err := database.GetDB().Create(role).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return enforcer, reader, writer
+}
+
+func newAccount(name string) *model.Account {
+	return &model.Account{Name: name, Password: "secret"}
+}
+
+func accountRoleNames(t *testing.T, id uint) []string {
+	t.Helper()
+	var names []string
+	err := database.GetDB().Table("roles").Joins("JOIN account_roles ON account_roles.role_id = roles.id").
+		Where("account_roles.account_id = ?", id).Order("roles.name").Pluck("roles.name", &names).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestAccountRoleSync(t *testing.T) {
+	enforcer, reader, writer := setupAccountRoles(t)
+	accounts := service.NewAccountService()
+	ctx := casbinService.WithSystemPrincipal(context.Background())
+
+	alice := newAccount("alice")
+	if err := accounts.CreateAccountWithRoles(ctx, alice, []model.Role{reader}); err != nil {
+		t.Fatalf("创建账户失败: %v", err)
+	}
+	subject := service.AccountSubject(alice.ID)
+	if !hasRole(enforcer, subject, reader.Name) {
+		t.Fatal("创建账户后缺少reader的g策略")
+	}
+	if err := accounts.ReplaceAccountRoles(ctx, alice, []model.Role{writer}); err != nil {
+		t.Fatalf("替换角色失败: %v", err)
+	}
+	if hasRole(enforcer, subject, reader.Name) || !hasRole(enforcer, subject, writer.Name) {
+		t.Errorf("替换角色后g策略不符: %v", enforcer.GetRolesForUser(subject))
+	}
+	if err := accounts.RemoveAccount(ctx, alice.ID); err != nil {
+		t.Fatalf("删除账户失败: %v", err)
+	}
+	if roles := enforcer.GetRolesForUser(subject); len(roles) != 0 {
+		t.Errorf("删除账户后g策略未删除: %v", roles)
+	}
+
+	bob := newAccount("bob")
+	if err := accounts.CreateAccountWithRoles(ctx, bob, []model.Role{reader, writer}); err != nil {
+		t.Fatal(err)
+	}
+	if err := accounts.DeleteAccountWithCleanup(ctx, bob.ID); err != nil {
+		t.Fatalf("删除账户失败: %v", err)
+	}
+	if roles := enforcer.GetRolesForUser(service.AccountSubject(bob.ID)); len(roles) != 0 {
+		t.Errorf("清理账户后g策略未删除: %v", roles)
+	}
+}
+
+func TestAccountRoleSyncCompensation(t *testing.T) {
+	enforcer, reader, writer := setupAccountRoles(t)
+	accounts := service.NewAccountService()
+	ctx := casbinService.WithSystemPrincipal(context.Background())
+	alice := newAccount("alice")
+	if err := accounts.CreateAccountWithRoles(ctx, alice, []model.Role{reader}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 删除策略表，使事务提交后的g策略写入失败
+	policyDB, err := database.Open(database.DriverMemory, testDBName(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policyDB.Migrator().DropTable(&casbinService.CasbinRule{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := accounts.ReplaceAccountRoles(ctx, alice, []model.Role{writer}); err == nil {
+		t.Fatal("写g策略失败时应返回错误")
+	}
+	if names := accountRoleNames(t, alice.ID); len(names) != 1 || names[0] != reader.Name {
+		t.Errorf("写g策略失败后账户角色应恢复为reader，实际 %v", names)
+	}
+	if !hasRole(enforcer, service.AccountSubject(alice.ID), reader.Name) {
+		t.Error("写g策略失败后内存中的g策略不应改变")
+	}
+
+	bob := newAccount("bob")
+	if err := accounts.CreateAccountWithRoles(ctx, bob, []model.Role{reader}); err == nil {
+		t.Fatal("写g策略失败时创建账户应返回错误")
+	}
+	var count int64
+	if err := database.GetDB().Unscoped().Model(&model.Account{}).Where("name = ?", bob.Name).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || len(accountRoleNames(t, bob.ID)) != 0 {
+		t.Errorf("写g策略失败后新建的账户应删除: count=%d", count)
+	}
+}
+
+func TestReconcileAccountRoles(t *testing.T) {
+	enforcer, reader, writer := setupAccountRoles(t)
+	accounts := service.NewAccountService()
+	ctx := casbinService.WithSystemPrincipal(context.Background())
+	alice, bob := newAccount("alice"), newAccount("bob")
+	for _, acc := range []*model.Account{alice, bob} {
+		if err := accounts.CreateAccountWithRoles(ctx, acc, []model.Role{reader}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	aliceSubject, bobSubject := service.AccountSubject(alice.ID), service.AccountSubject(bob.ID)
+
+	// 制造三种差异，非数字主体(直接配置给用户的g策略)不在对比范围内
+	if _, err := enforcer.DeleteRoleForUser(aliceSubject, reader.Name); err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range [][2]string{{bobSubject, writer.Name}, {"99999", reader.Name}, {"carol", reader.Name}} {
+		if _, err := enforcer.AddRoleForUser(rule[0], rule[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]service.RoleDrift{
+		aliceSubject: {Kind: service.RoleDriftMissing, Link: casbinService.RoleLink{User: aliceSubject, Role: reader.Name}},
+		bobSubject:   {Kind: service.RoleDriftExtra, Link: casbinService.RoleLink{User: bobSubject, Role: writer.Name}},
+		"99999":      {Kind: service.RoleDriftOrphan, Link: casbinService.RoleLink{User: "99999", Role: reader.Name}},
+	}
+	report, err := accounts.ReconcileAccountRoles(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Drifts) != len(want) {
+		t.Errorf("期望 %d 处差异，实际 %+v", len(want), report.Drifts)
+	}
+	for _, drift := range report.Drifts {
+		if expected := want[drift.Link.User]; drift != expected {
+			t.Errorf("期望 %+v，实际 %+v", expected, drift)
+		}
+	}
+	if hasRole(enforcer, aliceSubject, reader.Name) {
+		t.Error("不修复时不应改变g策略")
+	}
+
+	if report, err = accounts.ReconcileAccountRoles(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, drift := range report.Drifts {
+		if !drift.Repaired {
+			t.Errorf("差异未修复: %+v", drift)
+		}
+	}
+	if report, err = accounts.ReconcileAccountRoles(ctx, false); err != nil || len(report.Drifts) != 0 {
+		t.Errorf("修复后不应再有差异: %+v err=%v", report, err)
+	}
+	if !hasRole(enforcer, aliceSubject, reader.Name) || hasRole(enforcer, bobSubject, writer.Name) || !hasRole(enforcer, "carol", reader.Name) {
+		t.Error("修复结果不符，直接配置给用户的g策略不应删除")
+	}
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"context"
+	"go_casbin/internal/dto"
+	"go_casbin/internal/service/authz"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/jwt"
+	"testing"
+)
+
+func TestExplainUsesTokenSubjects(t *testing.T) {
+	setupPolicy(t, authPolicy, casbinService.CasbinOptions{})
+	service := authz.NewAuthzService()
+	ctx := context.Background()
+
+	// dave没有g规则，只有token中的admin角色能放行
+	req := dto.ExplainDTO{UserID: "dave", Roles: []string{"admin"}, Method: "PUT", Path: "/api/v1/policies/add"}
+	explanation, err := service.Explain(ctx, nil, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !explanation.Allowed || explanation.Subject != "admin" || len(explanation.MatchedPolicies) != 1 {
+		t.Errorf("期望经由token角色admin放行，实际 %+v", explanation)
+	}
+
+	// 解释当前账户自己的请求时使用登录账户的角色
+	account := &jwt.Account{ID: "dave", Role: []string{"admin"}}
+	req.Roles = nil
+	if explanation, err = service.Explain(ctx, account, req); err != nil || !explanation.Allowed {
+		t.Errorf("期望使用当前账户的token角色，实际 %+v err=%v", explanation, err)
+	}
+	if explanation, err = service.Explain(ctx, nil, req); err != nil || explanation.Allowed {
+		t.Errorf("没有角色的dave应被拒绝，实际 %+v err=%v", explanation, err)
+	}
+
+	req = dto.ExplainDTO{UserID: "alice", Method: "GET", Path: "/api/v1/accounts/:id"}
+	explanation, err = service.Explain(ctx, nil, req)
+	if err != nil || !explanation.Allowed || len(explanation.RoleChain) != 2 || explanation.RoleChain[1] != "reader" {
+		t.Errorf("期望alice经由reader放行，实际 %+v err=%v", explanation, err)
+	}
+}
+
+func TestExplainLoadsLazyDomain(t *testing.T) {
+	options := casbinService.CasbinOptions{Driver: "memory", DataSource: testDBName(t), EnableDomain: true}
+	writer := newInstances(t, 1, options)[0]
+	if _, err := writer.AddPolicyInDomain("admin", "t2", "/api/v1/accounts/:id", "PUT"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.AddRoleForUserInDomain("alice", "admin", "t2"); err != nil {
+		t.Fatal(err)
+	}
+
+	options.Domains, options.LazyDomains = []string{"t1"}, true
+	enforcer := newInstances(t, 1, options)[0]
+	if enforcer.DomainLoaded("t2") {
+		t.Fatal("t2不应在启动时加载")
+	}
+	account := &jwt.Account{ID: "alice", TenantId: "t2"}
+	req := dto.ExplainDTO{UserID: "alice", Method: "PUT", Path: "/api/v1/accounts/:id"}
+	explanation, err := authz.NewAuthzService().Explain(context.Background(), account, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !explanation.Allowed || !enforcer.DomainLoaded("t2") {
+		t.Errorf("期望先加载t2再解释，实际 %+v", explanation)
+	}
+}
@@ -0,0 +1,155 @@
+package test
+
+import (
+	"go_casbin/internal/logger"
+	casbinMiddleware "go_casbin/internal/middleware/casbin"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/jwt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const authPolicy = `p, reader, /api/v1/accounts/:id, GET
+p, admin, /api/v1/policies/*, *
+p, auditor, regex:/api/v1/(logs|audits)/[0-9]+, GET
+g, alice, reader
+g, bob, admin
+g, carol, auditor
+`
+
+// setupAuth 使用内置RBAC模型和临时策略文件初始化Casbin
+func setupAuth(t *testing.T) {
+	t.Helper()
+	logger.Init(nil)
+	gin.SetMode(gin.TestMode)
+	policyPath := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(policyPath, []byte(authPolicy), 0o644); err != nil {
+		t.Fatalf("写入策略文件失败: %v", err)
+	}
+	if err := casbinService.InitCasbin(casbinService.CasbinOptions{Driver: "file", DataSource: policyPath}); err != nil {
+		t.Fatalf("初始化Casbin失败: %v", err)
+	}
+}
+
+// newAuthRouter 模拟JWT中间件放入账户后再执行CasbinAuth
+func newAuthRouter(user string, auth gin.HandlerFunc, routes ...string) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("account", &jwt.Account{ID: user})
+		c.Next()
+	}, auth)
+	for _, route := range routes {
+		r.Any(route, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+	return r
+}
+
+func doRequest(r *gin.Engine, method, target string) int {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w.Code
+}
+
+func TestCasbinAuthRouteTemplate(t *testing.T) {
+	setupAuth(t)
+	routes := []string{"/api/v1/accounts/:id", "/api/v1/policies/*path", "/api/v1/logs/:id", "/api/v1/audits/:id"}
+	cases := []struct {
+		name   string
+		user   string
+		method string
+		target string
+		want   int
+	}{
+		{"路由参数按模板匹配", "alice", http.MethodGet, "/api/v1/accounts/123", http.StatusOK},
+		{"方法不匹配", "alice", http.MethodPut, "/api/v1/accounts/123", http.StatusForbidden},
+		{"未授权的路由", "alice", http.MethodGet, "/api/v1/policies/list", http.StatusForbidden},
+		{"通配符路径和方法", "bob", http.MethodDelete, "/api/v1/policies/list", http.StatusOK},
+		{"通配符不匹配其他前缀", "bob", http.MethodGet, "/api/v1/accounts/1", http.StatusForbidden},
+		{"未注册的路由退回请求路径", "bob", http.MethodGet, "/api/v1/unknown", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newAuthRouter(tc.user, casbinMiddleware.CasbinAuth(), routes...)
+			if got := doRequest(r, tc.method, tc.target); got != tc.want {
+				t.Errorf("%s %s: 期望 %d，实际 %d", tc.method, tc.target, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCasbinAuthRegex(t *testing.T) {
+	setupAuth(t)
+	// 正则策略按实际路径编写，使用PathObject
+	auth := casbinMiddleware.CasbinAuthWithOptions(casbinMiddleware.AuthOptions{Object: casbinMiddleware.PathObject})
+	r := newAuthRouter("carol", auth, "/api/v1/logs/:id", "/api/v1/audits/:id", "/api/v1/users/:id")
+	cases := map[string]int{
+		"/api/v1/logs/42":     http.StatusOK,
+		"/api/v1/audits/7":    http.StatusOK,
+		"/api/v1/logs/abc":    http.StatusForbidden,
+		"/api/v1/users/1":     http.StatusForbidden,
+		"/api/v1/logs/42/raw": http.StatusForbidden,
+	}
+	for target, want := range cases {
+		if got := doRequest(r, http.MethodGet, target); got != want {
+			t.Errorf("GET %s: 期望 %d，实际 %d", target, want, got)
+		}
+	}
+}
+
+func TestCasbinAuthCustomExtractor(t *testing.T) {
+	setupAuth(t)
+	// 所有写操作都按PUT鉴权
+	auth := casbinMiddleware.CasbinAuthWithOptions(casbinMiddleware.AuthOptions{
+		Action: func(c *gin.Context) string {
+			if c.Request.Method == http.MethodGet {
+				return http.MethodGet
+			}
+			return http.MethodPut
+		},
+	})
+	if _, err := casbinService.GetCasbinInstance().AddPolicy("alice", "/api/v1/accounts/:id", http.MethodPut); err != nil {
+		t.Fatalf("添加策略失败: %v", err)
+	}
+	r := newAuthRouter("alice", auth, "/api/v1/accounts/:id")
+	if got := doRequest(r, http.MethodPatch, "/api/v1/accounts/9"); got != http.StatusOK {
+		t.Errorf("PATCH 期望 200，实际 %d", got)
+	}
+	if got := doRequest(r, http.MethodGet, "/api/v1/accounts/9"); got != http.StatusOK {
+		t.Errorf("GET 期望 200，实际 %d", got)
+	}
+}
+
+func TestRequestValues(t *testing.T) {
+	if got := casbinMiddleware.RequestValues("", "/api/v1/accounts/:id", "GET"); !reflect.DeepEqual(got, []interface{}{"/api/v1/accounts/:id", "GET"}) {
+		t.Errorf("不带域的参数顺序错误: %v", got)
+	}
+	if got := casbinMiddleware.RequestValues("t1", "/api/v1/accounts/:id", "GET"); !reflect.DeepEqual(got, []interface{}{"t1", "/api/v1/accounts/:id", "GET"}) {
+		t.Errorf("带域的参数顺序错误: %v", got)
+	}
+}
+
+func TestPathMatch(t *testing.T) {
+	cases := []struct {
+		path, pattern string
+		want          bool
+	}{
+		{"/api/v1/accounts/:id", "/api/v1/accounts/:id", true},
+		{"/api/v1/accounts/123", "/api/v1/accounts/:id", true},
+		{"/api/v1/accounts/123/roles", "/api/v1/accounts/:id", false},
+		{"/api/v1/policies/a/b", "/api/v1/policies/*", true},
+		{"/api/v1/logs/1", "regex:/api/v1/(logs|audits)/[0-9]+", true},
+		{"/x/api/v1/logs/1", "regex:/api/v1/logs/[0-9]+", false},
+		{"/api/v1/logs/1", "regex:(", false},
+	}
+	for _, tc := range cases {
+		if got := casbinService.PathMatch(tc.path, tc.pattern); got != tc.want {
+			t.Errorf("PathMatch(%q, %q) = %v，期望 %v", tc.path, tc.pattern, got, tc.want)
+		}
+	}
+}
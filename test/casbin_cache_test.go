@@ -0,0 +1,52 @@
+package test
+
+import (
+	casbinService "go_casbin/pkg/casbin"
+	"testing"
+	"time"
+)
+
+func setupCache(t *testing.T) (*casbinService.CasbinEnforcer, *casbinService.DecisionCache) {
+	t.Helper()
+	enforcer := setupPolicy(t, authPolicy, casbinService.CasbinOptions{})
+	cache := casbinService.NewDecisionCache(casbinService.DecisionCacheOptions{Capacity: 100, TTL: time.Minute})
+	enforcer.SetDecisionCache(cache)
+	return enforcer, cache
+}
+
+func TestDecisionCacheInvalidation(t *testing.T) {
+	enforcer, cache := setupCache(t)
+	for i := 0; i < 2; i++ {
+		if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); !ok {
+			t.Fatal("alice应能查看账户")
+		}
+	}
+	if ok, _ := enforcer.Enforce("bob", "/api/v1/policies/list", "GET"); !ok {
+		t.Fatal("bob应能查看策略")
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Size != 2 {
+		t.Fatalf("命中统计不符: %+v", stats)
+	}
+
+	// 修改reader的策略只失效继承reader的alice，bob的结果仍在缓存中
+	if _, err := enforcer.RemovePolicy("reader", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	if stats := cache.Stats(); stats.Size != 1 {
+		t.Errorf("期望只失效alice的缓存，剩余 %d 条", stats.Size)
+	}
+	if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); ok {
+		t.Error("删除策略后仍使用了缓存中的允许结果")
+	}
+
+	// 收回角色同样失效该用户
+	if ok, _ := enforcer.Enforce("bob", "/api/v1/policies/list", "GET"); !ok {
+		t.Fatal("bob应能查看策略")
+	}
+	if _, err := enforcer.DeleteRoleForUser("bob", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := enforcer.Enforce("bob", "/api/v1/policies/list", "GET"); ok {
+		t.Error("收回角色后仍使用了缓存中的允许结果")
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"go_casbin/api"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/database"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPermissionCatalogDescriptions(t *testing.T) {
+	setupAuth(t)
+	if err := database.InitDB(database.DBOption{DBType: database.DriverMemory, DBName: testDBName(t)}); err != nil {
+		t.Fatalf("初始化内存数据库失败: %v", err)
+	}
+	r := gin.New()
+	api.RegisterRoutes(r)
+	api.BuildPermissionCatalog(r)
+	catalog := casbinService.GetCatalog()
+	defer catalog.Set(nil)
+
+	// 说明随路由一起登记，除匿名的测试接口外都不应退回权限名
+	descriptions := make(map[string]string)
+	for _, p := range catalog.List("") {
+		descriptions[p.Method+" "+p.Path] = p.Description
+		if !strings.Contains(p.Name, " ") && p.Description == p.Name {
+			t.Errorf("%s %s 缺少说明", p.Method, p.Path)
+		}
+	}
+	for _, key := range []string{"GET /api/v1/me/permissions", "GET /api/v1/policies/lint", "PUT /api/v1/accounts/:id"} {
+		if descriptions[key] == "" {
+			t.Errorf("%s 不在权限目录中", key)
+		}
+	}
+}
+
+func TestValidateAgainstCatalogLazyDomain(t *testing.T) {
+	options := casbinService.CasbinOptions{EnableDomain: true}
+	writer := newInstances(t, 1, options)[0]
+	if _, err := writer.AddPolicyInDomain("admin", "t2", "/api/v1/missing", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.AddPolicyInDomain("reader", "t1", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+
+	options.Domains, options.LazyDomains = []string{"t1"}, true
+	enforcer := newInstances(t, 1, options)[0]
+	catalog := casbinService.GetCatalog()
+	catalog.Set([]casbinService.Permission{{Method: "GET", Path: "/api/v1/accounts/:id"}})
+	defer catalog.Set(nil)
+
+	// t2未加载，其中指向不存在路由的策略同样要校验出来
+	issues, err := enforcer.ValidateAgainstCatalog(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Rule[1] != "t2" {
+		t.Errorf("期望校验出t2中的策略，实际 %+v", issues)
+	}
+	if enforcer.DomainLoaded("t2") {
+		t.Error("校验不应加载t2")
+	}
+}
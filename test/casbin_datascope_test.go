@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"go_casbin/internal/model"
+	workflow "go_casbin/internal/model/workFlow"
+	accountRepository "go_casbin/internal/repository/account"
+	"go_casbin/internal/service"
+	workFlowService "go_casbin/internal/service/workFlow"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/database"
+	"testing"
+)
+
+const dataScopePolicy = authPolicy + `p3, reader, account, department
+p3, auditor, *, tenant
+p3, admin, *, all
+`
+
+func TestResolveDataScope(t *testing.T) {
+	enforcer := setupPolicy(t, dataScopePolicy, casbinService.CasbinOptions{})
+	cases := []struct {
+		subjects []string
+		resource string
+		want     casbinService.DataScope
+	}{
+		{[]string{"alice"}, "account", casbinService.DataScopeDepartment},
+		{[]string{"alice"}, "workflow_instance", casbinService.DataScopeSelf},    // reader只配置了account
+		{[]string{"alice", "auditor"}, "account", casbinService.DataScopeTenant}, // 取最宽的范围
+		{[]string{"bob"}, "account", casbinService.DataScopeAll},
+		{[]string{"dave"}, "account", casbinService.DataScopeSelf},
+	}
+	for _, tc := range cases {
+		p := &casbinService.Principal{UserID: tc.subjects[0], Subjects: tc.subjects}
+		if got := enforcer.ResolveDataScope(p, tc.resource); got != tc.want {
+			t.Errorf("%v 在 %s 上期望 %s，实际 %s", tc.subjects, tc.resource, tc.want, got)
+		}
+	}
+
+	// 按范围逐级收窄：主体缺少部门时退回本人
+	row := casbinService.DataScopeRow{Tenant: "t1", Department: "d1", Owner: "9"}
+	ctx := casbinService.WithPrincipal(context.Background(), &casbinService.Principal{UserID: "alice", TenantID: "t1", Subjects: []string{"alice"}})
+	if casbinService.DataScopeAllows(ctx, "account", row) {
+		t.Error("alice没有部门，不应看到他人的账户")
+	}
+	ctx = casbinService.WithPrincipal(context.Background(), &casbinService.Principal{UserID: "alice", TenantID: "t1", DepartmentID: "d1", Subjects: []string{"alice"}})
+	if !casbinService.DataScopeAllows(ctx, "account", row) {
+		t.Error("同部门的账户应可见")
+	}
+	if casbinService.DataScopeAllows(context.Background(), "account", row) {
+		t.Error("没有主体的上下文应拒绝全部数据")
+	}
+	if !casbinService.DataScopeAllows(casbinService.WithSystemPrincipal(ctx), "account", row) {
+		t.Error("系统内部调用不应过滤")
+	}
+	if casbinService.DataScopeAllows(casbinService.WithPrincipal(casbinService.WithSystemPrincipal(ctx), nil), "account", row) {
+		t.Error("清除主体后应拒绝全部数据")
+	}
+}
+
+func TestDataScopePerDomain(t *testing.T) {
+	enforcer := setupPolicy(t, domainPolicy, casbinService.CasbinOptions{EnableDomain: true})
+	if _, err := enforcer.SetDataScope("reader", "t1", "account", casbinService.DataScopeTenant); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enforcer.SetDataScope("reader", "", "account", casbinService.DataScopeAll); err == nil {
+		t.Error("启用多租户时缺少域应报错")
+	}
+	if rules := enforcer.GetDataScopes(); len(rules) != 1 || len(rules[0]) != 4 || rules[0][1] != "t1" {
+		t.Fatalf("p3规则应带域字段: %v", rules)
+	}
+	// 角色只在t1中配置了数据范围，bob在t2中同样是reader
+	alice := &casbinService.Principal{UserID: "alice", Domain: "t1", Subjects: []string{"alice"}}
+	bob := &casbinService.Principal{UserID: "bob", Domain: "t2", Subjects: []string{"bob"}}
+	if got := enforcer.ResolveDataScope(alice, "account"); got != casbinService.DataScopeTenant {
+		t.Errorf("alice在t1中期望tenant，实际 %s", got)
+	}
+	if got := enforcer.ResolveDataScope(bob, "account"); got != casbinService.DataScopeSelf {
+		t.Errorf("bob在t2中期望self，实际 %s", got)
+	}
+	if ok, err := enforcer.RemoveDataScope("reader", "t1", "account"); err != nil || !ok {
+		t.Errorf("删除数据范围失败: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestAccountRepositoryDataScope(t *testing.T) {
+	setupPolicy(t, dataScopePolicy, casbinService.CasbinOptions{})
+	if err := database.InitDB(database.DBOption{DBType: database.DriverMemory, DBName: testDBName(t) + "_business"}); err != nil {
+		t.Fatalf("初始化内存数据库失败: %v", err)
+	}
+	if err := database.AutoMigrate(&model.Account{}, &model.Role{}); err != nil {
+		t.Fatal(err)
+	}
+	repository := accountRepository.NewAccountRepository()
+	background := context.Background()
+	own := &model.Account{Name: "own", Password: "secret", TenantID: "t1", DepartmentID: "d1"}
+	other := &model.Account{Name: "other", Password: "secret", TenantID: "t1", DepartmentID: "d2"}
+	for _, acc := range []*model.Account{own, other} {
+		if err := repository.Create(background, acc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// alice继承reader，对账户的数据范围为本部门
+	ctx := casbinService.WithPrincipal(background, &casbinService.Principal{UserID: "alice", TenantID: "t1", DepartmentID: "d1", Subjects: []string{"alice"}})
+	if accounts, err := repository.FindByCondition(ctx, "1 = 1"); err != nil || len(accounts) != 1 || accounts[0].ID != own.ID {
+		t.Errorf("只应查到本部门的账户: %v err=%v", accounts, err)
+	}
+	other.Status = 0
+	if err := repository.Update(ctx, other); !errors.Is(err, accountRepository.ErrAccountNotFound) {
+		t.Errorf("更新范围外的账户期望ErrAccountNotFound，实际 %v", err)
+	}
+	if err := repository.ReplaceAccountRoles(ctx, other, nil); !errors.Is(err, accountRepository.ErrAccountNotFound) {
+		t.Errorf("修改范围外账户的角色期望ErrAccountNotFound，实际 %v", err)
+	}
+	if err := repository.Delete(ctx, other.ID); !errors.Is(err, accountRepository.ErrAccountNotFound) {
+		t.Errorf("删除范围外的账户期望ErrAccountNotFound，实际 %v", err)
+	}
+	accounts := service.NewAccountService()
+	if err := accounts.UpdateAccountWithRoles(ctx, other, nil); !errors.Is(err, accountRepository.ErrAccountNotFound) {
+		t.Errorf("事务更新范围外的账户期望ErrAccountNotFound，实际 %v", err)
+	}
+	if err := accounts.DeleteAccountWithCleanup(ctx, other.ID); !errors.Is(err, accountRepository.ErrAccountNotFound) {
+		t.Errorf("事务删除范围外的账户期望ErrAccountNotFound，实际 %v", err)
+	}
+	if acc, err := repository.FindByID(casbinService.WithSystemPrincipal(background), other.ID); err != nil || acc == nil || acc.Status != 1 {
+		t.Errorf("范围外的账户不应被修改: %+v err=%v", acc, err)
+	}
+
+	own.Status = 0
+	if err := repository.Update(ctx, own); err != nil {
+		t.Errorf("更新本部门的账户失败: %v", err)
+	}
+	if err := repository.Delete(ctx, own.ID); err != nil {
+		t.Errorf("删除本部门的账户失败: %v", err)
+	}
+}
+
+func TestWorkflowInstanceDataScope(t *testing.T) {
+	setupPolicy(t, dataScopePolicy, casbinService.CasbinOptions{})
+	instances := workFlowService.NewWorkFlowServiceImpl()
+	background := context.Background()
+	for i, owner := range []string{"alice", "bob"} {
+		instance := &workflow.WorkflowInstance{ID: int64(i + 1), TenantID: "t1", DepartmentID: "d1", CreatedBy: owner}
+		if err := instances.CreateWorkFlowInstance(background, instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// reader没有配置工作流实例的数据范围，alice只能看到自己发起的实例
+	ctx := casbinService.WithPrincipal(background, &casbinService.Principal{UserID: "alice", TenantID: "t1", DepartmentID: "d1", Subjects: []string{"alice"}})
+	if list, err := instances.ListWorkFlowInstances(ctx); err != nil || len(list) != 1 || list[0].CreatedBy != "alice" {
+		t.Errorf("列表只应包含本人发起的实例: %v err=%v", list, err)
+	}
+	if _, err := instances.GetWorkFlowInstance(ctx, "2"); err == nil {
+		t.Error("范围外的实例应按不存在处理")
+	}
+	if err := instances.UpdateWorkFlowInstance(ctx, "2", &workflow.WorkflowInstance{CreatedBy: "alice"}); err == nil {
+		t.Error("不应更新范围外的实例")
+	}
+	if err := instances.DeleteWorkFlowInstance(ctx, "2"); err == nil {
+		t.Error("不应删除范围外的实例")
+	}
+	if _, err := instances.GetWorkFlowInstance(casbinService.WithPrincipal(ctx, nil), "2"); err == nil {
+		t.Error("没有主体时应按不存在处理")
+	}
+	if _, err := instances.GetWorkFlowInstance(casbinService.WithSystemPrincipal(ctx), "2"); err != nil {
+		t.Errorf("系统内部调用不应过滤: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"go_casbin/internal/logger"
+	casbinMiddleware "go_casbin/internal/middleware/casbin"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/jwt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const domainPolicy = `p, reader, t1, /api/v1/accounts/:id, GET
+p, admin, t2, /api/v1/accounts/:id, *
+g, alice, reader, t1
+g, alice, admin, t2
+g, bob, reader, t2
+`
+
+// setupPolicy 使用临时策略文件初始化Casbin，options中的Driver和DataSource由该函数填写
+func setupPolicy(t *testing.T, policy string, options casbinService.CasbinOptions) *casbinService.CasbinEnforcer {
+	t.Helper()
+	logger.Init(nil)
+	gin.SetMode(gin.TestMode)
+	policyPath := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(policyPath, []byte(policy), 0o644); err != nil {
+		t.Fatalf("写入策略文件失败: %v", err)
+	}
+	options.Driver, options.DataSource = "file", policyPath
+	if err := casbinService.InitCasbin(options); err != nil {
+		t.Fatalf("初始化Casbin失败: %v", err)
+	}
+	return casbinService.GetCasbinInstance()
+}
+
+func TestEnforceWithDomain(t *testing.T) {
+	enforcer := setupPolicy(t, domainPolicy, casbinService.CasbinOptions{EnableDomain: true})
+	cases := []struct {
+		sub, dom, act string
+		want          bool
+	}{
+		{"alice", "t1", "GET", true},
+		{"alice", "t1", "PUT", false},
+		{"alice", "t2", "PUT", true},
+		{"bob", "t2", "GET", false}, // t2中的reader没有策略
+		{"bob", "t1", "GET", false}, // 角色只在t2中分配
+	}
+	for _, tc := range cases {
+		got, err := enforcer.EnforceWithDomain(tc.sub, tc.dom, "/api/v1/accounts/:id", tc.act)
+		if err != nil {
+			t.Fatalf("鉴权失败: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("(%s, %s, %s) 期望 %v，实际 %v", tc.sub, tc.dom, tc.act, tc.want, got)
+		}
+	}
+	if roles := enforcer.GetRolesForUserInDomain("alice", "t2"); len(roles) != 1 || roles[0] != "admin" {
+		t.Errorf("alice在t2中的角色不符: %v", roles)
+	}
+}
+
+func TestCasbinAuthDomainFromToken(t *testing.T) {
+	setupPolicy(t, domainPolicy, casbinService.CasbinOptions{EnableDomain: true})
+	newRouter := func(tenant string) *gin.Engine {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("account", &jwt.Account{ID: "alice", TenantId: tenant})
+			c.Next()
+		}, casbinMiddleware.CasbinAuth())
+		r.Any("/api/v1/accounts/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+	if got := doRequest(newRouter("t1"), http.MethodGet, "/api/v1/accounts/1"); got != http.StatusOK {
+		t.Errorf("t1 GET 期望 200，实际 %d", got)
+	}
+	if got := doRequest(newRouter("t1"), http.MethodPut, "/api/v1/accounts/1"); got != http.StatusForbidden {
+		t.Errorf("t1 PUT 期望 403，实际 %d", got)
+	}
+	if got := doRequest(newRouter("t2"), http.MethodPut, "/api/v1/accounts/1"); got != http.StatusOK {
+		t.Errorf("t2 PUT 期望 200，实际 %d", got)
+	}
+	if got := doRequest(newRouter(""), http.MethodGet, "/api/v1/accounts/1"); got != http.StatusForbidden {
+		t.Errorf("缺少租户时期望 403，实际 %d", got)
+	}
+}
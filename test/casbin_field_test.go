@@ -0,0 +1,83 @@
+package test
+
+import (
+	"encoding/json"
+	casbinMiddleware "go_casbin/internal/middleware/casbin"
+	"go_casbin/internal/middleware/response"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/jwt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const fieldPolicy = authPolicy + `p4, admin, profile#*, *
+p4, reader, profile#email, read
+`
+
+func TestFieldPermission(t *testing.T) {
+	setupPolicy(t, fieldPolicy, casbinService.CasbinOptions{})
+	casbinMiddleware.RegisterFields("profile",
+		casbinMiddleware.FieldRule{Field: "phone", Mask: casbinMiddleware.MaskMiddle(3, 4)},
+		casbinMiddleware.FieldRule{Field: "email", Mask: casbinMiddleware.MaskEmail},
+		casbinMiddleware.FieldRule{Field: "salary"},
+	)
+	serve := func(user, method, body string) (int, map[string]interface{}) {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("account", &jwt.Account{ID: user})
+			c.Next()
+		}, casbinMiddleware.FieldPermission("profile"))
+		r.Any("/profile", func(c *gin.Context) {
+			response.Success(c, gin.H{
+				"name":    "alice",
+				"phone":   "13812345678",
+				"email":   "alice@example.com",
+				"salary":  1000,
+				"friends": []gin.H{{"name": "bob", "phone": "13900001111"}},
+			})
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/profile", strings.NewReader(body)))
+		var result struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result.Data
+	}
+
+	// 没有读权限的字段脱敏或去除，嵌套对象中的同名字段一并处理
+	code, data := serve("alice", http.MethodGet, "")
+	if code != http.StatusOK {
+		t.Fatalf("期望 200，实际 %d", code)
+	}
+	if data["phone"] != "138****5678" || data["email"] != "alice@example.com" || data["name"] != "alice" {
+		t.Errorf("alice的返回字段不符: %v", data)
+	}
+	if _, ok := data["salary"]; ok {
+		t.Error("没有Mask的字段应直接去除")
+	}
+	if friend := data["friends"].([]interface{})[0].(map[string]interface{}); friend["phone"] != "139****1111" {
+		t.Errorf("嵌套对象中的字段未脱敏: %v", friend)
+	}
+	if _, data = serve("bob", http.MethodGet, ""); data["phone"] != "13812345678" || data["salary"] == nil {
+		t.Errorf("admin应看到全部字段: %v", data)
+	}
+	if _, data = serve("dave", http.MethodGet, ""); data["email"] != "a***@example.com" {
+		t.Errorf("没有任何字段权限时邮箱应脱敏: %v", data)
+	}
+
+	// 写入没有write权限的字段时拒绝
+	if code, _ = serve("alice", http.MethodPut, `{"name": "alice2", "email": "a@b.c"}`); code != http.StatusForbidden {
+		t.Errorf("reader写email期望 403，实际 %d", code)
+	}
+	if code, _ = serve("alice", http.MethodPut, `{"name": "alice2"}`); code != http.StatusOK {
+		t.Errorf("只写未受保护的字段期望 200，实际 %d", code)
+	}
+	if code, _ = serve("bob", http.MethodPut, `{"phone": "13800000000"}`); code != http.StatusOK {
+		t.Errorf("admin写phone期望 200，实际 %d", code)
+	}
+}
@@ -0,0 +1,203 @@
+package test
+
+import (
+	"go_casbin/internal/model/audit"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/database"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// setupGrants 在内存数据库上启用限时角色授权，授权和审计日志与策略在同一个库
+func setupGrants(t *testing.T) (*casbinService.CasbinEnforcer, *gorm.DB) {
+	t.Helper()
+	enforcer := newInstances(t, 1, casbinService.CasbinOptions{})[0]
+	db, err := database.Open(database.DriverMemory, testDBName(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enforcer.EnableRoleGrants(db); err != nil {
+		t.Fatalf("启用限时授权失败: %v", err)
+	}
+	if _, err := enforcer.AddPolicy("reader", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	return enforcer, db
+}
+
+func grantStatus(t *testing.T, enforcer *casbinService.CasbinEnforcer, user string) map[uint]casbinService.RoleGrant {
+	t.Helper()
+	grants, _, err := enforcer.ListGrants(user, "", 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[uint]casbinService.RoleGrant, len(grants))
+	for _, grant := range grants {
+		result[grant.ID] = grant
+	}
+	return result
+}
+
+func hasRole(enforcer *casbinService.CasbinEnforcer, user, role string) bool {
+	for _, r := range enforcer.GetRolesForUser(user) {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRoleGrantLifecycle(t *testing.T) {
+	enforcer, db := setupGrants(t)
+	start := time.Now().Add(time.Hour)
+	end := start.Add(time.Hour)
+	grant, err := enforcer.GrantRole(casbinService.RoleGrantRequest{User: "alice", Role: "reader", StartAt: start, EndAt: &end, Operator: "admin"})
+	if err != nil {
+		t.Fatalf("创建授权失败: %v", err)
+	}
+	if grant.Status != casbinService.GrantPending || hasRole(enforcer, "alice", "reader") {
+		t.Fatalf("未到生效时间不应写入g策略: %+v", grant)
+	}
+
+	if err := enforcer.SyncGrants(start); err != nil {
+		t.Fatal(err)
+	}
+	if got := grantStatus(t, enforcer, "alice")[grant.ID]; got.Status != casbinService.GrantActive || !got.LinkAdded {
+		t.Errorf("到达生效时间后期望active，实际 %+v", got)
+	}
+	if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); !ok {
+		t.Error("授权生效后alice应继承reader")
+	}
+
+	if err := enforcer.SyncGrants(end); err != nil {
+		t.Fatal(err)
+	}
+	if got := grantStatus(t, enforcer, "alice")[grant.ID]; got.Status != casbinService.GrantExpired {
+		t.Errorf("到达过期时间后期望expired，实际 %+v", got)
+	}
+	if hasRole(enforcer, "alice", "reader") {
+		t.Error("授权过期后g策略未删除")
+	}
+
+	// 创建、生效、过期各记录一条审计日志
+	var actions []string
+	if err := db.Model(&audit.AuditLog{}).Where("record_id = ?", grant.ID).Order("id").Pluck("action", &actions).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"role_grant_create", "role_grant_active", "role_grant_expired"}
+	if len(actions) != len(want) {
+		t.Fatalf("审计日志不符: %v", actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("第%d条审计日志期望 %s，实际 %s", i+1, want[i], actions[i])
+		}
+	}
+}
+
+func TestRoleGrantHandOver(t *testing.T) {
+	enforcer, _ := setupGrants(t)
+	now := time.Now()
+	firstEnd, secondEnd := now.Add(time.Hour), now.Add(2*time.Hour)
+	first, err := enforcer.GrantRole(casbinService.RoleGrantRequest{User: "alice", Role: "reader", EndAt: &firstEnd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := enforcer.GrantRole(casbinService.RoleGrantRequest{User: "alice", Role: "reader", EndAt: &secondEnd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.LinkAdded || second.LinkAdded {
+		t.Fatalf("g策略应由第一个授权添加: first=%v second=%v", first.LinkAdded, second.LinkAdded)
+	}
+
+	// 第一个授权过期时把g策略交给仍在生效的第二个授权
+	if err := enforcer.SyncGrants(firstEnd); err != nil {
+		t.Fatal(err)
+	}
+	grants := grantStatus(t, enforcer, "alice")
+	if grants[first.ID].Status != casbinService.GrantExpired || !grants[second.ID].LinkAdded {
+		t.Errorf("交接后状态不符: first=%+v second=%+v", grants[first.ID], grants[second.ID])
+	}
+	if !hasRole(enforcer, "alice", "reader") {
+		t.Error("还有生效中的授权，g策略不应删除")
+	}
+	if _, err := enforcer.RevokeGrant(second.ID, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if hasRole(enforcer, "alice", "reader") {
+		t.Error("最后一个授权撤销后g策略未删除")
+	}
+	if _, err := enforcer.RevokeGrant(second.ID, "admin"); err == nil {
+		t.Error("已撤销的授权不能再次撤销")
+	}
+}
+
+// grantExpiringSoon 为alice添加100ms后过期的reader授权，过期前的鉴权结果写入缓存
+func grantExpiringSoon(t *testing.T, enforcer *casbinService.CasbinEnforcer) *casbinService.RoleGrant {
+	t.Helper()
+	enforcer.SetDecisionCache(casbinService.NewDecisionCache(casbinService.DecisionCacheOptions{Capacity: 100, TTL: time.Minute}))
+	end := time.Now().Add(100 * time.Millisecond)
+	grant, err := enforcer.GrantRole(casbinService.RoleGrantRequest{User: "alice", Role: "reader", EndAt: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision, err := enforcer.EnforceSubjects(casbinService.StrategyAnyAllow, []string{"alice"}, "/api/v1/accounts/:id", "GET"); err != nil || !decision.Allowed {
+		t.Fatalf("授权生效期间应放行: %+v err=%v", decision, err)
+	}
+	time.Sleep(time.Until(end))
+	return grant
+}
+
+func TestCheckGrantsOnEnforce(t *testing.T) {
+	enforcer, _ := setupGrants(t)
+	grant := grantExpiringSoon(t, enforcer)
+
+	// 到期后的第一次鉴权即拒绝，不等待后台的状态变更，也不使用过期前缓存的结果
+	decision, err := enforcer.EnforceSubjects(casbinService.StrategyAnyAllow, []string{"alice"}, "/api/v1/accounts/:id", "GET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Allowed {
+		t.Error("授权到期后的第一次鉴权应拒绝")
+	}
+	if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); ok {
+		t.Error("授权过期后仍能访问")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for grantStatus(t, enforcer, "alice")[grant.ID].Status != casbinService.GrantExpired {
+		if time.Now().After(deadline) {
+			t.Fatal("2秒内鉴权触发的过期处理未完成")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hasRole(enforcer, "alice", "reader") {
+		t.Error("过期处理完成后g策略未删除")
+	}
+}
+
+func TestExpiredGrantDeniedWhenTransitionFails(t *testing.T) {
+	enforcer, db := setupGrants(t)
+	grantExpiringSoon(t, enforcer)
+	// 授权表不可用，后台的状态变更一直失败
+	if err := db.Migrator().DropTable(&casbinService.RoleGrant{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		decision, err := enforcer.EnforceSubjects(casbinService.StrategyAnyAllow, []string{"alice"}, "/api/v1/accounts/:id", "GET")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decision.Allowed {
+			t.Fatalf("第%d次鉴权: 状态变更失败时到期的授权仍不应放行", i+1)
+		}
+		// 重新加载策略会恢复角色继承关系，下一次鉴权时应再次隐藏
+		time.Sleep(20 * time.Millisecond)
+		if err := enforcer.LoadPolicy(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	casbinService "go_casbin/pkg/casbin"
+	"reflect"
+	"testing"
+)
+
+func TestEnforceMany(t *testing.T) {
+	setupAuth(t)
+	results, err := casbinService.GetCasbinInstance().EnforceMany([][]interface{}{
+		{"alice", "/api/v1/accounts/:id", "GET"},
+		{"alice", "/api/v1/accounts/:id", "PUT"},
+		{"bob", "/api/v1/policies/list", "DELETE"},
+	})
+	if err != nil {
+		t.Fatalf("批量鉴权失败: %v", err)
+	}
+	if want := []bool{true, false, true}; !reflect.DeepEqual(results, want) {
+		t.Errorf("期望 %v，实际 %v", want, results)
+	}
+}
+
+func TestAllowedPermissions(t *testing.T) {
+	setupAuth(t)
+	catalog := casbinService.GetCatalog()
+	catalog.Set([]casbinService.Permission{
+		{Name: "account.GetAccount", Method: "GET", Path: "/api/v1/accounts/:id"},
+		{Name: "account.UpdateAccount", Method: "PUT", Path: "/api/v1/accounts/:id"},
+		{Name: "policy.ListPolicies", Method: "GET", Path: "/api/v1/policies/list"},
+		{Name: "policy.RemovePolicy", Method: "DELETE", Path: "/api/v1/policies/list"},
+	})
+	defer catalog.Set(nil)
+
+	enforcer := casbinService.GetCasbinInstance()
+	if _, err := enforcer.AddPolicy("reader", "menu:accounts", "view"); err != nil {
+		t.Fatalf("添加策略失败: %v", err)
+	}
+	if _, err := enforcer.AddRoleForUser("bob", "reader"); err != nil {
+		t.Fatalf("添加角色失败: %v", err)
+	}
+
+	// bob经admin展开通配符路由，经reader继承账户权限和菜单权限
+	got, err := enforcer.AllowedPermissions(casbinService.StrategyAnyAllow, []string{"bob"}, "", "")
+	if err != nil {
+		t.Fatalf("获取权限失败: %v", err)
+	}
+	want := []casbinService.AllowedPermission{
+		{Object: "/api/v1/accounts/:id", Action: "GET", Name: "account.GetAccount"},
+		{Object: "/api/v1/policies/list", Action: "DELETE", Name: "policy.RemovePolicy"},
+		{Object: "/api/v1/policies/list", Action: "GET", Name: "policy.ListPolicies"},
+		{Object: "menu:accounts", Action: "view"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("期望 %v，实际 %v", want, got)
+	}
+
+	// 按资源前缀过滤，token中的角色同样参与
+	got, err = enforcer.AllowedPermissions(casbinService.StrategyAnyAllow, []string{"nobody", "reader"}, "", "menu:")
+	if err != nil {
+		t.Fatalf("获取权限失败: %v", err)
+	}
+	if want := []casbinService.AllowedPermission{{Object: "menu:accounts", Action: "view"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("期望 %v，实际 %v", want, got)
+	}
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"context"
+	casbinMiddleware "go_casbin/internal/middleware/casbin"
+	casbinService "go_casbin/pkg/casbin"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireRelation(t *testing.T) {
+	setupAuth(t)
+	owners := map[string]string{"1": "alice", "2": "bob"}
+	casbinService.GetRelationChecker().Register("test_account", func(ctx context.Context, id string) (casbinService.Relations, error) {
+		owner, ok := owners[id]
+		if !ok {
+			return nil, casbinService.ErrResourceNotFound
+		}
+		return casbinService.Relations{casbinService.RelationOwner: {owner}}, nil
+	})
+
+	// 与api.go相同：先由CasbinAuth校验接口权限，再校验与具体资源的关系
+	newRouter := func(user string) *gin.Engine {
+		r := newAuthRouter(user, casbinMiddleware.CasbinAuth())
+		r.GET("/api/v1/accounts/:id", casbinMiddleware.RequireRelation("test_account", "id", casbinService.RelationOwner), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+	cases := []struct {
+		name   string
+		user   string
+		target string
+		want   int
+	}{
+		{"所有者", "alice", "/api/v1/accounts/1", http.StatusOK},
+		{"非所有者", "alice", "/api/v1/accounts/2", http.StatusForbidden},
+		{"资源不存在", "alice", "/api/v1/accounts/3", http.StatusNotFound},
+		{"所有者但没有接口权限", "bob", "/api/v1/accounts/2", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		if got := doRequest(newRouter(tc.user), http.MethodGet, tc.target); got != tc.want {
+			t.Errorf("%s: 期望 %d，实际 %d", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"fmt"
+	casbinService "go_casbin/pkg/casbin"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// anyActionModel 在内置模型的基础上允许策略以ANY表示任意方法，用于确认新模型已生效
+var anyActionModel = strings.Replace(casbinService.RBACModel, `p.act == "*")`, `p.act == "*" || p.act == "ANY")`, 1)
+
+func TestReloadModelRejectsBadModel(t *testing.T) {
+	enforcer := newInstances(t, 1, casbinService.CasbinOptions{ModelPath: writeModel(t, casbinService.RBACModel)})[0]
+	if _, err := enforcer.AddPolicy("reader", "/api/v1/accounts/:id", "ANY"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enforcer.AddRoleForUser("alice", "reader"); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := map[string]string{
+		"语法错误":     "[request_definition]\nr = sub, obj, act\n[matchers]\nm = (",
+		"缺少策略定义":   "[request_definition]\nr = sub, obj, act\n[policy_effect]\ne = some(where (p.eft == allow))\n[matchers]\nm = r.sub == p.sub",
+		"未定义的角色":   strings.Replace(anyActionModel, "g(r.sub, p.sub)", "g2(r.sub, p.sub)", 1),
+		"请求参数个数不符": strings.Replace(anyActionModel, "r = sub, obj, act", "r = sub, dom, obj, act", 1),
+	}
+	for name, content := range bad {
+		if err := enforcer.ReloadModel(writeModel(t, content)); err == nil {
+			t.Errorf("%s: 期望拒绝加载", name)
+		}
+	}
+	// 加载失败时继续使用原模型
+	if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); ok {
+		t.Error("原模型不支持ANY，加载失败后不应放行")
+	}
+	if err := enforcer.ReloadModel(writeModel(t, anyActionModel)); err != nil {
+		t.Fatalf("加载新模型失败: %v", err)
+	}
+	if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); !ok {
+		t.Error("新模型生效后应放行")
+	}
+}
+
+func TestReloadModelKeepsConcurrentChanges(t *testing.T) {
+	enforcer := newInstances(t, 1, casbinService.CasbinOptions{ModelPath: writeModel(t, casbinService.RBACModel)})[0]
+	if _, err := enforcer.AddPolicy("reader", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enforcer.AddRoleForUser("alice", "reader"); err != nil {
+		t.Fatal(err)
+	}
+	// 策略较多时构建新执行器需要一段时间，修改更容易落在构建期间
+	for batch := 0; batch < 4; batch++ {
+		rules := make([][]string, 0, 500)
+		for i := 0; i < cap(rules); i++ {
+			rules = append(rules, []string{"writer", fmt.Sprintf("/api/v1/documents/%d/%d", batch, i), "PUT"})
+		}
+		if _, err := enforcer.AddPolicies(rules); err != nil {
+			t.Fatal(err)
+		}
+	}
+	models := []string{writeModel(t, anyActionModel), writeModel(t, casbinService.RBACModel)}
+
+	// 加载模型的同时修改策略和鉴权：修改不能丢失，鉴权不能看到未加载完成的执行器
+	var wg sync.WaitGroup
+	var writes, denied atomic.Int32
+	done := make(chan struct{})
+	running := func(fn func() bool) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if !fn() {
+					return
+				}
+			}
+		}()
+	}
+	running(func() bool {
+		if _, err := enforcer.AddRoleForUser(fmt.Sprintf("user%d", writes.Load()), "reader"); err != nil {
+			t.Error(err)
+			return false
+		}
+		writes.Add(1)
+		return true
+	})
+	running(func() bool {
+		if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); !ok {
+			denied.Add(1)
+		}
+		return true
+	})
+	for i := 0; i < 10; i++ {
+		if err := enforcer.ReloadModel(models[i%len(models)]); err != nil {
+			t.Fatalf("加载模型失败: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if n := denied.Load(); n > 0 {
+		t.Errorf("加载模型期间有 %d 次鉴权被拒绝", n)
+	}
+	for i := 0; i < int(writes.Load()); i++ {
+		user := fmt.Sprintf("user%d", i)
+		if !hasRole(enforcer, user, "reader") {
+			t.Errorf("%s 的角色在加载模型后丢失", user)
+		}
+	}
+}
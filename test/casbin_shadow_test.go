@@ -0,0 +1,84 @@
+package test
+
+import (
+	"go_casbin/internal/config"
+	casbinMiddleware "go_casbin/internal/middleware/casbin"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestShadowModeGroups(t *testing.T) {
+	setupAuth(t)
+	saved := *config.Middleware()
+	t.Cleanup(func() { config.SetMiddleware(saved) })
+	cfg := saved
+	cfg.CasbinMode = "enforce"
+	cfg.CasbinGroups = map[string]string{"shadow-test": "shadow", "off-test": "off"}
+	config.SetMiddleware(cfg)
+
+	// 统计在进程内累计，按本次运行的增量校验
+	before := casbinMiddleware.GetShadowStats()["shadow-test"]
+
+	// 影子模式下将被拒绝的请求继续执行，并在上下文中标记
+	var shadowDenied []bool
+	r := newAuthRouter("alice", casbinMiddleware.CasbinAuthGroup("shadow-test"))
+	r.Any("/api/v1/accounts/:id", func(c *gin.Context) {
+		shadowDenied = append(shadowDenied, c.GetBool(casbinMiddleware.ShadowDeniedKey))
+		c.Status(http.StatusOK)
+	})
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		if got := doRequest(r, method, "/api/v1/accounts/1"); got != http.StatusOK {
+			t.Errorf("影子模式 %s 期望 200，实际 %d", method, got)
+		}
+	}
+	if len(shadowDenied) != 2 || shadowDenied[0] || !shadowDenied[1] {
+		t.Errorf("影子模式的拒绝标记不符: %v", shadowDenied)
+	}
+	if stats := casbinMiddleware.GetShadowStats()["shadow-test"]; stats.Evaluated-before.Evaluated != 2 || stats.Denied-before.Denied != 1 {
+		t.Errorf("影子模式统计不符: %+v", stats)
+	}
+
+	if got := doRequest(newAuthRouter("nobody", casbinMiddleware.CasbinAuthGroup("off-test"), "/api/v1/accounts/:id"), http.MethodPut, "/api/v1/accounts/1"); got != http.StatusOK {
+		t.Errorf("off模式期望跳过鉴权，实际 %d", got)
+	}
+	if _, ok := casbinMiddleware.GetShadowStats()["off-test"]; ok {
+		t.Error("off模式不应计入影子统计")
+	}
+	// 未单独配置的组使用casbinMode
+	if got := doRequest(newAuthRouter("alice", casbinMiddleware.CasbinAuthGroup("enforce-test"), "/api/v1/accounts/:id"), http.MethodPut, "/api/v1/accounts/1"); got != http.StatusForbidden {
+		t.Errorf("enforce模式期望 403，实际 %d", got)
+	}
+}
+
+// TestModeHotReload 配置热更新与请求并发读取鉴权模式，配合-race检查数据竞争
+func TestModeHotReload(t *testing.T) {
+	saved := *config.Middleware()
+	t.Cleanup(func() { config.SetMiddleware(saved) })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			cfg := saved
+			cfg.CasbinMode = "enforce"
+			cfg.CasbinGroups = map[string]string{"reload-test": "shadow"}
+			if i%2 == 1 {
+				cfg.CasbinGroups["reload-test"] = "off"
+			}
+			config.SetMiddleware(cfg)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			if got := casbinMiddleware.ModeOf("reload-test"); got != casbinMiddleware.ModeOff {
+				t.Errorf("热更新后应读取最新配置，实际 %s", got)
+			}
+			return
+		default:
+			casbinMiddleware.ModeOf("reload-test")
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"go_casbin/internal/logger"
+	casbinService "go_casbin/pkg/casbin"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// eftModel 带eft字段的RBAC模型，允许且没有命中deny时放行
+const eftModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
+
+[matchers]
+m = g(r.sub, p.sub) && pathMatch(r.obj, p.obj) && (r.act == p.act || p.act == "*")
+`
+
+const eftPolicy = `p, reader, /api/v1/accounts/:id, GET, allow
+p, suspended, /api/v1/accounts/:id, *, deny
+g, alice, reader
+`
+
+func writeModel(t *testing.T, content string) string {
+	t.Helper()
+	modelPath := filepath.Join(t.TempDir(), "model.conf")
+	if err := os.WriteFile(modelPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("写入模型文件失败: %v", err)
+	}
+	return modelPath
+}
+
+func TestEnforceSubjectsStrategy(t *testing.T) {
+	modelPath := writeModel(t, eftModel)
+	enforcer := setupPolicy(t, eftPolicy, casbinService.CasbinOptions{ModelPath: modelPath, Strategy: casbinService.StrategyDenyOverrides})
+	if enforcer.Strategy() != casbinService.StrategyDenyOverrides {
+		t.Fatalf("合并策略不符: %s", enforcer.Strategy())
+	}
+	subjects := []string{"alice", "suspended"}
+
+	decision, err := enforcer.EnforceSubjects(casbinService.StrategyAnyAllow, subjects, "/api/v1/accounts/:id", "GET")
+	if err != nil || !decision.Allowed || decision.Subject != "alice" {
+		t.Errorf("any-allow 期望alice放行，实际 %+v err=%v", decision, err)
+	}
+	decision, err = enforcer.EnforceSubjects(casbinService.StrategyDenyOverrides, subjects, "/api/v1/accounts/:id", "GET")
+	if err != nil || decision.Allowed || decision.Subject != "suspended" {
+		t.Errorf("deny-overrides 期望suspended的deny生效，实际 %+v err=%v", decision, err)
+	}
+	decision, err = enforcer.EnforceSubjects(casbinService.StrategyDenyOverrides, []string{"alice", "nobody"}, "/api/v1/accounts/:id", "GET")
+	if err != nil || !decision.Allowed {
+		t.Errorf("未命中deny时期望放行，实际 %+v err=%v", decision, err)
+	}
+
+	// 权限列表使用相同的合并规则
+	got, err := enforcer.AllowedPermissions(casbinService.StrategyDenyOverrides, subjects, "", "")
+	if err != nil || len(got) != 0 {
+		t.Errorf("deny-overrides下不应列出被拒绝的权限: %v err=%v", got, err)
+	}
+	got, err = enforcer.AllowedPermissions(casbinService.StrategyAnyAllow, subjects, "", "")
+	if err != nil || len(got) != 1 {
+		t.Errorf("any-allow下期望1项权限: %v err=%v", got, err)
+	}
+}
+
+func TestDenyOverridesRequiresEft(t *testing.T) {
+	logger.Init(nil)
+	policyPath := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(policyPath, []byte(authPolicy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := casbinService.InitCasbin(casbinService.CasbinOptions{Driver: "file", DataSource: policyPath, Strategy: casbinService.StrategyDenyOverrides})
+	if err == nil {
+		t.Fatal("内置模型没有eft字段，deny-overrides应初始化失败")
+	}
+}
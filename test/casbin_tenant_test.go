@@ -0,0 +1,157 @@
+package test
+
+import (
+	casbinService "go_casbin/pkg/casbin"
+	"testing"
+	"time"
+)
+
+// setupTenants 先由加载全部域的实例写入t1、t2的策略，再创建t1常驻、其他域按需加载的实例
+func setupTenants(t *testing.T) (writer, lazy *casbinService.CasbinEnforcer, cache *casbinService.DecisionCache) {
+	t.Helper()
+	options := casbinService.CasbinOptions{EnableDomain: true}
+	writer = newInstances(t, 1, options)[0]
+	for _, rule := range [][]string{{"reader", "t1", "/api/v1/accounts/:id", "GET"}, {"admin", "t2", "/api/v1/accounts/:id", "*"}} {
+		if _, err := writer.AddPolicyInDomain(rule[0], rule[1], rule[2], rule[3]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, link := range [][]string{{"alice", "reader", "t1"}, {"bob", "admin", "t2"}} {
+		if _, err := writer.AddRoleForUserInDomain(link[0], link[1], link[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	options.Domains, options.LazyDomains = []string{"t1"}, true
+	lazy = newInstances(t, 1, options)[0]
+	cache = casbinService.NewDecisionCache(casbinService.DecisionCacheOptions{Capacity: 100, TTL: time.Minute})
+	lazy.SetDecisionCache(cache)
+	return writer, lazy, cache
+}
+
+func TestLazyDomainLoadAndEvict(t *testing.T) {
+	_, lazy, cache := setupTenants(t)
+	if lazy.DomainLoaded("t2") {
+		t.Fatal("t2不应在启动时加载")
+	}
+	for i := 0; i < 2; i++ {
+		if ok, _ := lazy.EnforceWithDomain("alice", "t1", "/api/v1/accounts/:id", "GET"); !ok {
+			t.Fatal("alice在t1中应能查看账户")
+		}
+	}
+
+	// 首次请求t2时加载，只失效t2规则涉及的主体，alice在t1中的结果仍在缓存中
+	if ok, _ := lazy.EnforceWithDomain("bob", "t2", "/api/v1/accounts/:id", "PUT"); !ok || !lazy.DomainLoaded("t2") {
+		t.Fatalf("期望加载t2后放行bob: ok=%v loaded=%v", ok, lazy.DomainLoaded("t2"))
+	}
+	if ok, _ := lazy.EnforceWithDomain("alice", "t1", "/api/v1/accounts/:id", "GET"); !ok {
+		t.Fatal("alice在t1中应能查看账户")
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Size != 2 {
+		t.Fatalf("加载t2后alice的缓存应保留: %+v", stats)
+	}
+
+	// 移除t2只失效bob的缓存
+	if err := lazy.EvictDomain("t1"); err == nil {
+		t.Error("常驻的域不能移除")
+	}
+	if err := lazy.EvictDomain("t2"); err != nil {
+		t.Fatal(err)
+	}
+	if stats := cache.Stats(); lazy.DomainLoaded("t2") || stats.Size != 1 {
+		t.Fatalf("移除t2后只应保留alice的缓存: loaded=%v %+v", lazy.DomainLoaded("t2"), stats)
+	}
+	if ok, _ := lazy.EnforceWithDomain("alice", "t1", "/api/v1/accounts/:id", "GET"); !ok || cache.Stats().Hits != 3 {
+		t.Errorf("移除t2后alice应命中缓存: ok=%v %+v", ok, cache.Stats())
+	}
+	if ok, _ := lazy.EnforceWithDomain("bob", "t2", "/api/v1/accounts/:id", "PUT"); !ok || !lazy.DomainLoaded("t2") {
+		t.Error("移除后再次请求应重新加载t2")
+	}
+}
+
+func TestLazyDomainWatcher(t *testing.T) {
+	writer, lazy, _ := setupTenants(t)
+	bus := &watcherBus{}
+	for _, e := range []*casbinService.CasbinEnforcer{writer, lazy} {
+		if err := e.SetWatcher(bus.join()); err != nil {
+			t.Fatalf("设置watcher失败: %v", err)
+		}
+	}
+
+	// t2未加载时忽略其变更消息，加载时从存储读取最新策略
+	if _, err := writer.AddRoleForUserInDomain("carol", "admin", "t2"); err != nil {
+		t.Fatal(err)
+	}
+	if lazy.DomainLoaded("t2") {
+		t.Fatal("变更消息不应加载t2")
+	}
+	if ok, _ := lazy.EnforceWithDomain("carol", "t2", "/api/v1/accounts/:id", "PUT"); !ok {
+		t.Fatal("加载t2时应读取未加载期间新增的角色")
+	}
+
+	// t2加载后增量同步变更并失效carol的缓存
+	if _, err := writer.DeleteRoleForUserInDomain("carol", "admin", "t2"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := lazy.EnforceWithDomain("carol", "t2", "/api/v1/accounts/:id", "PUT"); ok {
+		t.Error("已加载的域未同步删除的角色")
+	}
+
+	// 移除t2后的变更在重新加载时生效
+	if err := lazy.EvictDomain("t2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.AddRoleForUserInDomain("dave", "admin", "t2"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := lazy.EnforceWithDomain("dave", "t2", "/api/v1/accounts/:id", "PUT"); !ok {
+		t.Error("重新加载t2时应读取移除期间新增的角色")
+	}
+	if ok, _ := lazy.EnforceWithDomain("alice", "t2", "/api/v1/accounts/:id", "PUT"); ok {
+		t.Error("alice在t2中没有角色，不应放行")
+	}
+}
+
+// TestLazyDomainPolicyAdmin 策略管理接口修改或查询未加载的域时先加载该域
+func TestLazyDomainPolicyAdmin(t *testing.T) {
+	_, lazy, _ := setupTenants(t)
+	evict := func() {
+		t.Helper()
+		if err := lazy.EvictDomain("t2"); err != nil || lazy.DomainLoaded("t2") {
+			t.Fatalf("移除t2失败: %v", err)
+		}
+	}
+
+	if rules := lazy.GetFilteredPolicy(1, "t2"); len(rules) != 1 {
+		t.Errorf("查询未加载的域应返回存储中的策略: %v", rules)
+	}
+	evict()
+	if users := lazy.GetUsersForRoleInDomain("admin", "t2"); len(users) != 1 || users[0] != "bob" {
+		t.Errorf("查询未加载的域的角色用户: %v", users)
+	}
+
+	evict()
+	if ok, err := lazy.RemovePolicyInDomain("admin", "t2", "/api/v1/accounts/:id", "*"); err != nil || !ok {
+		t.Fatalf("删除未加载的域的策略: ok=%v err=%v", ok, err)
+	}
+	evict()
+	if ok, err := lazy.DeleteRoleForUserInDomain("bob", "admin", "t2"); err != nil || !ok {
+		t.Fatalf("删除未加载的域的角色: ok=%v err=%v", ok, err)
+	}
+	evict()
+	if ok, err := lazy.AddPolicyInDomain("auditor", "t2", "/api/v1/accounts/:id", "GET"); err != nil || !ok {
+		t.Fatalf("向未加载的域添加策略: ok=%v err=%v", ok, err)
+	}
+	if !lazy.HasPolicy("auditor", "t2", "/api/v1/accounts/:id", "GET") {
+		t.Error("添加的策略应进入内存")
+	}
+
+	// 重新从存储加载全部策略，确认删除和添加已写入存储
+	stored := newInstances(t, 1, casbinService.CasbinOptions{EnableDomain: true})[0]
+	if stored.HasPolicy("admin", "t2", "/api/v1/accounts/:id", "*") || len(stored.GetRolesForUserInDomain("bob", "t2")) != 0 {
+		t.Error("删除的策略和角色仍在存储中")
+	}
+	if !stored.HasPolicy("auditor", "t2", "/api/v1/accounts/:id", "GET") {
+		t.Error("添加的策略未写入存储")
+	}
+}
@@ -0,0 +1,281 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go_casbin/internal/logger"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/redis"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// watcherBus 进程内的消息总线，模拟etcd/redis把一个实例的变更广播给其他实例
+type watcherBus struct {
+	mu       sync.Mutex
+	watchers []*busWatcher
+}
+
+type busWatcher struct {
+	bus      *watcherBus
+	callback func(string)
+}
+
+var _ persist.WatcherEx = (*busWatcher)(nil)
+
+func (b *watcherBus) join() *busWatcher {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w := &busWatcher{bus: b}
+	b.watchers = append(b.watchers, w)
+	return w
+}
+
+func (w *busWatcher) send(msg casbinService.PolicyMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	w.bus.mu.Lock()
+	peers := append([]*busWatcher(nil), w.bus.watchers...)
+	w.bus.mu.Unlock()
+	for _, peer := range peers {
+		if peer != w && peer.callback != nil {
+			peer.callback(string(payload))
+		}
+	}
+	return nil
+}
+
+func (w *busWatcher) SetUpdateCallback(callback func(string)) error {
+	w.callback = callback
+	return nil
+}
+func (w *busWatcher) Update() error {
+	return w.send(casbinService.PolicyMessage{Method: casbinService.Update})
+}
+func (w *busWatcher) Close() {}
+func (w *busWatcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.send(casbinService.PolicyMessage{Method: casbinService.UpdateForAddPolicy, Sec: sec, Ptype: ptype, NewRules: [][]string{params}})
+}
+func (w *busWatcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.send(casbinService.PolicyMessage{Method: casbinService.UpdateForRemovePolicy, Sec: sec, Ptype: ptype, NewRules: [][]string{params}})
+}
+func (w *busWatcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.send(casbinService.PolicyMessage{Method: casbinService.UpdateForRemoveFilteredPolicy, Sec: sec, Ptype: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues})
+}
+func (w *busWatcher) UpdateForSavePolicy(model.Model) error {
+	return w.send(casbinService.PolicyMessage{Method: casbinService.UpdateForSavePolicy})
+}
+func (w *busWatcher) UpdateForAddPolicies(sec, ptype string, rules ...[]string) error {
+	return w.send(casbinService.PolicyMessage{Method: casbinService.UpdateForAddPolicies, Sec: sec, Ptype: ptype, NewRules: rules})
+}
+func (w *busWatcher) UpdateForRemovePolicies(sec, ptype string, rules ...[]string) error {
+	return w.send(casbinService.PolicyMessage{Method: casbinService.UpdateForRemovePolicies, Sec: sec, Ptype: ptype, NewRules: rules})
+}
+
+var (
+	testDBNames sync.Map // *testing.T -> 内存库名
+	testDBSeq   atomic.Int64
+)
+
+// testDBName 测试使用的内存库名，同一个测试内返回同一个库名；每次运行使用新库名，go test -count=N时不会读到上一次运行留下的数据
+func testDBName(t *testing.T) string {
+	if name, ok := testDBNames.Load(t); ok {
+		return name.(string)
+	}
+	name := fmt.Sprintf("%s_%d", t.Name(), testDBSeq.Add(1))
+	testDBNames.Store(t, name)
+	t.Cleanup(func() { testDBNames.Delete(t) })
+	return name
+}
+
+// newInstances 在同一个内存数据库上初始化count个执行器，模拟多副本部署
+func newInstances(t *testing.T, count int, options casbinService.CasbinOptions) []*casbinService.CasbinEnforcer {
+	t.Helper()
+	logger.Init(nil)
+	options.Driver = "memory"
+	options.DataSource = testDBName(t)
+	instances := make([]*casbinService.CasbinEnforcer, 0, count)
+	for i := 0; i < count; i++ {
+		if err := casbinService.InitCasbin(options); err != nil {
+			t.Fatalf("初始化Casbin失败: %v", err)
+		}
+		instances = append(instances, casbinService.GetCasbinInstance())
+	}
+	return instances
+}
+
+func TestWatcherSyncsInstances(t *testing.T) {
+	instances := newInstances(t, 2, casbinService.CasbinOptions{})
+	a, b := instances[0], instances[1]
+	bus := &watcherBus{}
+	for _, e := range instances {
+		if err := e.SetWatcher(bus.join()); err != nil {
+			t.Fatalf("设置watcher失败: %v", err)
+		}
+	}
+
+	if _, err := a.AddPolicy("reader", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AddRoleForUser("alice", "reader"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := b.Enforce("alice", "/api/v1/accounts/:id", "GET"); !ok {
+		t.Error("其他实例未同步新增的策略和角色")
+	}
+	if _, err := a.DeleteRoleForUser("alice", "reader"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := b.Enforce("alice", "/api/v1/accounts/:id", "GET"); ok {
+		t.Error("其他实例未同步删除的角色")
+	}
+	if _, err := a.RemoveFilteredPolicy(0, "reader"); err != nil {
+		t.Fatal(err)
+	}
+	if rules := b.GetPolicy(); len(rules) != 0 {
+		t.Errorf("其他实例未同步按条件删除的策略: %v", rules)
+	}
+}
+
+// recordingWatcher 用假的publish创建MessageWatcher，记录发出的消息和收到的回调
+func recordingWatcher() (*casbinService.MessageWatcher, *[]string, *[]string) {
+	var published, received []string
+	w := casbinService.NewMessageWatcher(func(payload string) error {
+		published = append(published, payload)
+		return nil
+	}, nil)
+	_ = w.SetUpdateCallback(func(payload string) {
+		received = append(received, payload)
+	})
+	return w, &published, &received
+}
+
+func decodePolicyMessage(t *testing.T, payload string) casbinService.PolicyMessage {
+	t.Helper()
+	var msg casbinService.PolicyMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		t.Fatalf("解析策略变更消息失败: %v", err)
+	}
+	return msg
+}
+
+func TestMessageWatcherIgnoresOwnMessages(t *testing.T) {
+	w, published, received := recordingWatcher()
+	if err := w.UpdateForAddPolicy("p", "p", "reader", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*published) != 1 {
+		t.Fatalf("应发出1条消息，实际 %d 条", len(*published))
+	}
+	w.Receive((*published)[0])
+	if len(*received) != 0 {
+		t.Errorf("本实例发出的消息不应触发回调: %v", *received)
+	}
+}
+
+func TestMessageWatcherDeliversPeerMessages(t *testing.T) {
+	w, _, received := recordingWatcher()
+	peer, published, _ := recordingWatcher()
+	rule := []string{"reader", "/api/v1/accounts/:id", "GET"}
+	newRule := []string{"reader", "/api/v1/accounts/:id", "PUT"}
+
+	cases := []struct {
+		name string
+		send func() error
+		want casbinService.PolicyMessage
+	}{
+		{"全量", peer.Update, casbinService.PolicyMessage{Method: casbinService.Update}},
+		{"新增", func() error { return peer.UpdateForAddPolicy("p", "p", rule...) },
+			casbinService.PolicyMessage{Method: casbinService.UpdateForAddPolicy, Sec: "p", Ptype: "p", NewRules: [][]string{rule}}},
+		{"批量删除", func() error { return peer.UpdateForRemovePolicies("g", "g", []string{"alice", "reader"}) },
+			casbinService.PolicyMessage{Method: casbinService.UpdateForRemovePolicies, Sec: "g", Ptype: "g", NewRules: [][]string{{"alice", "reader"}}}},
+		{"按条件删除", func() error { return peer.UpdateForRemoveFilteredPolicy("p", "p", 1, "/api/v1/accounts/:id") },
+			casbinService.PolicyMessage{Method: casbinService.UpdateForRemoveFilteredPolicy, Sec: "p", Ptype: "p", FieldIndex: 1, FieldValues: []string{"/api/v1/accounts/:id"}}},
+		{"更新", func() error { return peer.UpdateForUpdatePolicy("p", "p", rule, newRule) },
+			casbinService.PolicyMessage{Method: casbinService.UpdateForUpdatePolicy, Sec: "p", Ptype: "p", OldRules: [][]string{rule}, NewRules: [][]string{newRule}}},
+	}
+	for i, tc := range cases {
+		if err := tc.send(); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		w.Receive((*published)[i])
+		if len(*received) != i+1 {
+			t.Fatalf("%s: 其他实例的消息应触发回调", tc.name)
+		}
+		got := decodePolicyMessage(t, (*received)[i])
+		if got.ID == "" {
+			t.Errorf("%s: 消息缺少发送方实例ID", tc.name)
+		}
+		got.ID = ""
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: 回调收到 %+v，期望 %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMessageWatcherMalformedPayload(t *testing.T) {
+	w, _, received := recordingWatcher()
+	w.Receive("{not json")
+	if len(*received) != 1 {
+		t.Fatalf("无法解析的消息应触发回调，实际 %d 次", len(*received))
+	}
+	if msg := decodePolicyMessage(t, (*received)[0]); msg.Method != casbinService.Update {
+		t.Errorf("无法解析的消息应按全量变更处理，实际 %s", msg.Method)
+	}
+
+	// 接入执行器后，无法解析的消息触发从存储全量加载
+	instances := newInstances(t, 2, casbinService.CasbinOptions{})
+	a, b := instances[0], instances[1]
+	bw := casbinService.NewMessageWatcher(func(string) error { return nil }, nil)
+	if err := b.SetWatcher(bw); err != nil {
+		t.Fatalf("设置watcher失败: %v", err)
+	}
+	if _, err := a.AddPolicy("reader", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	if b.HasPolicy("reader", "/api/v1/accounts/:id", "GET") {
+		t.Fatal("未广播前其他实例不应看到新策略")
+	}
+	bw.Receive("{not json")
+	if !b.HasPolicy("reader", "/api/v1/accounts/:id", "GET") {
+		t.Error("无法解析的消息应触发全量重新加载")
+	}
+}
+
+// TestRedisWatcher 需要本地redis，未启动时跳过
+func TestRedisWatcher(t *testing.T) {
+	if err := redis.InitRedis(redis.RedisOptions{Addr: "localhost:6379"}); err != nil {
+		t.Skipf("本地redis不可用: %v", err)
+	}
+	client := redis.GetRedisInstance()
+	instances := newInstances(t, 2, casbinService.CasbinOptions{})
+	channel := "casbin:policy:test:" + time.Now().Format("150405.000000")
+	for _, e := range instances {
+		w, err := casbinService.NewRedisWatcher(&client, channel)
+		if err != nil {
+			t.Skipf("本地redis不可用: %v", err)
+		}
+		defer w.Close()
+		if err := e.SetWatcher(w); err != nil {
+			t.Fatalf("设置watcher失败: %v", err)
+		}
+	}
+	if _, err := instances[0].AddPolicy("reader", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !instances[1].HasPolicy("reader", "/api/v1/accounts/:id", "GET") {
+		if time.Now().After(deadline) {
+			t.Fatal("2秒内未收到redis广播的策略变更")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"context"
+	"go_casbin/internal/logger"
+	"go_casbin/internal/model"
+	"go_casbin/internal/service"
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/database"
+	"testing"
+)
+
+// TestMemoryDatabase 业务库和策略库都使用内存数据库，不依赖外部数据库即可完成账户和策略的读写
+func TestMemoryDatabase(t *testing.T) {
+	logger.Init(nil)
+	if err := database.InitDB(database.DBOption{DBType: database.DriverMemory, DBName: testDBName(t) + "_business"}); err != nil {
+		t.Fatalf("初始化内存数据库失败: %v", err)
+	}
+	if err := database.AutoMigrate(&model.Account{}, &model.Role{}); err != nil {
+		t.Fatalf("创建数据表失败: %v", err)
+	}
+	if err := casbinService.InitCasbin(casbinService.CasbinOptions{Driver: database.DriverMemory, DataSource: testDBName(t)}); err != nil {
+		t.Fatalf("初始化Casbin失败: %v", err)
+	}
+	enforcer := casbinService.GetCasbinInstance()
+	if _, err := enforcer.AddPolicy("reader", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatalf("添加策略失败: %v", err)
+	}
+
+	role := model.Role{Name: "reader"}
+	if err := database.GetDB().Create(&role).Error; err != nil {
+		t.Fatalf("创建角色失败: %v", err)
+	}
+	account := &model.Account{Name: "alice", Password: "secret"}
+	if err := service.NewAccountService().CreateAccountWithRoles(context.Background(), account, []model.Role{role}); err != nil {
+		t.Fatalf("创建账户失败: %v", err)
+	}
+	subject := service.AccountSubject(account.ID)
+	if ok, err := enforcer.Enforce(subject, "/api/v1/accounts/:id", "GET"); err != nil || !ok {
+		t.Errorf("账户应继承reader角色的权限: ok=%v err=%v", ok, err)
+	}
+
+	// 重新加载策略，确认策略已写入内存数据库
+	if err := enforcer.LoadPolicy(); err != nil {
+		t.Fatalf("重新加载策略失败: %v", err)
+	}
+	if roles := enforcer.GetRolesForUser(subject); len(roles) != 1 || roles[0] != "reader" {
+		t.Errorf("重新加载后账户角色不符: %v", roles)
+	}
+	lines, err := enforcer.ExportPolicies()
+	if err != nil || len(lines) != 2 {
+		t.Errorf("导出策略: lines=%v err=%v", lines, err)
+	}
+}
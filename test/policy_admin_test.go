@@ -0,0 +1,48 @@
+package test
+
+import (
+	"context"
+	"go_casbin/internal/dto"
+	"go_casbin/internal/logger"
+	"go_casbin/internal/service/policy"
+	casbinService "go_casbin/pkg/casbin"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPolicyAdminPersistsFile 文件存储下管理接口的修改写回策略文件，重新初始化后仍然生效
+func TestPolicyAdminPersistsFile(t *testing.T) {
+	logger.Init(nil)
+	policyPath := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(policyPath, []byte(authPolicy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	options := casbinService.CasbinOptions{Driver: "file", DataSource: policyPath}
+	if err := casbinService.InitCasbin(options); err != nil {
+		t.Fatalf("初始化Casbin失败: %v", err)
+	}
+	ctx := context.Background()
+	service := policy.NewPolicyService()
+	if ok, err := service.AddPolicy(ctx, dto.PolicyDTO{Sub: "editor", Obj: "/api/v1/accounts/:id", Act: "PUT"}); err != nil || !ok {
+		t.Fatalf("添加策略失败: ok=%v err=%v", ok, err)
+	}
+	if ok, err := service.AssignRole(ctx, dto.RoleAssignDTO{User: "dave", Role: "editor"}); err != nil || !ok {
+		t.Fatalf("分配角色失败: ok=%v err=%v", ok, err)
+	}
+	if ok, err := service.UnassignRole(ctx, dto.RoleAssignDTO{User: "alice", Role: "reader"}); err != nil || !ok {
+		t.Fatalf("收回角色失败: ok=%v err=%v", ok, err)
+	}
+
+	// 模拟重启：从策略文件重新初始化
+	if err := casbinService.InitCasbin(options); err != nil {
+		t.Fatalf("重新初始化Casbin失败: %v", err)
+	}
+	enforcer := casbinService.GetCasbinInstance()
+	if ok, _ := enforcer.Enforce("dave", "/api/v1/accounts/:id", "PUT"); !ok {
+		t.Error("重启后新增的策略和角色丢失")
+	}
+	if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); ok {
+		t.Error("重启后已收回的角色仍然生效")
+	}
+}
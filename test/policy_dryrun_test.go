@@ -0,0 +1,47 @@
+package test
+
+import (
+	casbinService "go_casbin/pkg/casbin"
+	"testing"
+)
+
+func TestDryRunReportsFlips(t *testing.T) {
+	enforcer := setupPolicy(t, authPolicy, casbinService.CasbinOptions{})
+	for _, sub := range []string{"alice", "bob"} {
+		if _, err := enforcer.EnforceSubjects(casbinService.StrategyAnyAllow, []string{sub}, "/api/v1/accounts/:id", "GET"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if recent := enforcer.RecentRequests(); len(recent) != 2 || recent[0][0] != "bob" {
+		t.Fatalf("最近的鉴权请求不符: %v", recent)
+	}
+
+	// 未指定请求时使用最近的鉴权请求
+	change := casbinService.PolicyChange{
+		Remove: []casbinService.PolicyLine{{Ptype: "g", Rule: []string{"alice", "reader"}}},
+		Add:    []casbinService.PolicyLine{{Ptype: "g", Rule: []string{"bob", "reader"}}},
+	}
+	result, err := enforcer.DryRun(change, nil)
+	if err != nil {
+		t.Fatalf("预演失败: %v", err)
+	}
+	if result.Total != 2 || len(result.Revoked) != 1 || result.Revoked[0].Request[0] != "alice" ||
+		len(result.Granted) != 1 || result.Granted[0].Request[0] != "bob" {
+		t.Errorf("预演结果不符: %+v", result)
+	}
+	if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); !ok {
+		t.Error("预演修改了线上策略")
+	}
+
+	requests := [][]string{{"carol", "/api/v1/logs/1", "GET"}}
+	result, err = enforcer.DryRun(casbinService.PolicyChange{Remove: []casbinService.PolicyLine{{Ptype: "p", Rule: []string{"reader", "/api/v1/accounts/:id", "GET"}}}}, requests)
+	if err != nil || result.Total != 1 || len(result.Granted)+len(result.Revoked) != 0 {
+		t.Errorf("与变更无关的请求不应改变结果: %+v err=%v", result, err)
+	}
+	if _, err := enforcer.DryRun(casbinService.PolicyChange{}, nil); err == nil {
+		t.Error("空变更应被拒绝")
+	}
+	if _, err := enforcer.DryRun(casbinService.PolicyChange{Add: []casbinService.PolicyLine{{Ptype: "p2", Rule: []string{"x"}}}}, nil); err == nil {
+		t.Error("模型中没有的ptype应被拒绝")
+	}
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"go_casbin/internal/logger"
+	casbinService "go_casbin/pkg/casbin"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const lintPolicy = `p, reader, /api/v1/accounts/:id, GET
+p, reader, /api/v1/accounts/:id, GET
+p, admin, /api/v1/policies/*, *
+p, admin, /api/v1/policies/list, GET
+p, bob, /api/v1/policies/export, GET
+p, retired, /api/v1/logs/:id, GET
+p, ghost, /api/v1/audits/:id, GET
+g, alice, reader
+g, bob, admin
+g, carol, empty
+g, auditor, reviewer
+g, a, b
+g, b, c
+g, c, a
+`
+
+func TestLintPolicy(t *testing.T) {
+	logger.Init(nil)
+	policyPath := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(policyPath, []byte(lintPolicy), 0o644); err != nil {
+		t.Fatalf("写入策略文件失败: %v", err)
+	}
+	if err := casbinService.InitCasbin(casbinService.CasbinOptions{Driver: "file", DataSource: policyPath}); err != nil {
+		t.Fatalf("初始化Casbin失败: %v", err)
+	}
+	issues, err := casbinService.GetCasbinInstance().LintPolicy()
+	if err != nil {
+		t.Fatalf("检查策略失败: %v", err)
+	}
+
+	got := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		got = append(got, issue.Kind+" "+issue.Ptype+" "+strings.Join(issue.Rule, ","))
+	}
+	sort.Strings(got)
+	want := []string{
+		"cycle g a,b,c,a",
+		"duplicate p reader,/api/v1/accounts/:id,GET",
+		"empty-role g empty",
+		"empty-role g reviewer",
+		"orphan p ghost",
+		"orphan p retired",
+		"shadowed p admin,/api/v1/policies/list,GET",
+		"shadowed p bob,/api/v1/policies/export,GET",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("检查结果不符\n期望: %v\n实际: %v", want, got)
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	casbinService "go_casbin/pkg/casbin"
+	"testing"
+)
+
+func TestSnapshotDiffAndRollback(t *testing.T) {
+	enforcer := newInstances(t, 1, casbinService.CasbinOptions{})[0]
+	if err := enforcer.EnableSnapshots(nil); err != nil {
+		t.Fatalf("启用快照失败: %v", err)
+	}
+	if _, err := enforcer.AddPolicy("reader", "/api/v1/accounts/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enforcer.AddRoleForUser("alice", "reader"); err != nil {
+		t.Fatal(err)
+	}
+	base, err := enforcer.CreateSnapshot("admin", "初始策略")
+	if err != nil {
+		t.Fatalf("保存快照失败: %v", err)
+	}
+	if base.RuleCount != 2 {
+		t.Errorf("快照规则数不符: %d", base.RuleCount)
+	}
+
+	if _, err := enforcer.AddPolicy("reader", "/api/v1/accounts/:id", "PUT"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enforcer.DeleteRoleForUser("alice", "reader"); err != nil {
+		t.Fatal(err)
+	}
+	diff, err := enforcer.DiffSnapshots(base.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Rule[2] != "PUT" || len(diff.Removed) != 1 || diff.Removed[0].Ptype != "g" {
+		t.Errorf("与当前策略的差异不符: %+v", diff)
+	}
+
+	backup, err := enforcer.Rollback(base.ID, "admin")
+	if err != nil {
+		t.Fatalf("回滚失败: %v", err)
+	}
+	if ok, _ := enforcer.Enforce("alice", "/api/v1/accounts/:id", "GET"); !ok {
+		t.Error("回滚后alice的角色未恢复")
+	}
+	if enforcer.HasPolicy("reader", "/api/v1/accounts/:id", "PUT") {
+		t.Error("回滚后快照之后新增的策略仍然存在")
+	}
+	// 回滚前自动备份的快照与回滚前的策略一致，可以撤销回滚
+	undo, err := enforcer.DiffSnapshots(base.ID, backup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undo.Added) != 1 || len(undo.Removed) != 1 {
+		t.Errorf("自动备份的快照与回滚前的策略不一致: %+v", undo)
+	}
+	if now, err := enforcer.DiffSnapshots(base.ID, 0); err != nil || len(now.Added)+len(now.Removed) != 0 {
+		t.Errorf("回滚后与快照仍有差异: %+v err=%v", now, err)
+	}
+
+	snapshots, total, err := enforcer.ListSnapshots(1, 10)
+	if err != nil || total != 2 || snapshots[0].ID != backup.ID || len(snapshots[0].Rules) != 0 {
+		t.Errorf("快照列表不符: total=%d err=%v", total, err)
+	}
+	if _, err := enforcer.Rollback(999, "admin"); err == nil {
+		t.Error("回滚到不存在的快照应失败")
+	}
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"go_casbin/internal/logger"
+	casbinService "go_casbin/pkg/casbin"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolicySuites(t *testing.T) {
+	logger.Init(nil)
+	casbinService.AssertPolicySuite(t, "testdata/policies/*.yaml")
+}
+
+func TestPolicySuiteDiffAndUpdate(t *testing.T) {
+	logger.Init(nil)
+	dir := t.TempDir()
+	policy, err := os.ReadFile("testdata/policies/rbac.policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "rbac.policy.csv"), policy, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "suite.yaml")
+	suite := `policy: rbac.policy.csv
+cases:
+  # 期望错误，应当失败
+  - name: 读者修改账户
+    sub: alice
+    obj: /api/v1/accounts/:id
+    act: PUT
+    allow: true
+  - name: 管理员查看策略
+    sub: bob
+    obj: /api/v1/policies/list
+    act: GET
+    allow: false
+`
+	if err := os.WriteFile(file, []byte(suite), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := casbinService.RunPolicySuite(file)
+	if err != nil {
+		t.Fatalf("执行用例失败: %v", err)
+	}
+	if len(result.Failures()) != 2 {
+		t.Fatalf("期望2个失败用例，实际 %d", len(result.Failures()))
+	}
+	diff := result.Diff()
+	for _, want := range []string{"--- FAIL 读者修改账户", "期望: allow", "实际: allow，命中策略 [admin, /api/v1/policies/*, *]"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("差异报告缺少 %q:\n%s", want, diff)
+		}
+	}
+
+	changed, err := casbinService.UpdatePolicySuite(file)
+	if err != nil || changed != 2 {
+		t.Fatalf("改写用例文件: changed=%d err=%v", changed, err)
+	}
+	data, _ := os.ReadFile(file)
+	if !strings.Contains(string(data), "# 期望错误，应当失败") {
+		t.Errorf("改写后丢失注释:\n%s", data)
+	}
+	if result, err = casbinService.RunPolicySuite(file); err != nil || !result.Passed() {
+		t.Errorf("改写后仍有失败用例: %v\n%s", err, result.Diff())
+	}
+}
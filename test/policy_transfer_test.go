@@ -0,0 +1,102 @@
+package test
+
+import (
+	casbinService "go_casbin/pkg/casbin"
+	"go_casbin/pkg/database"
+	"reflect"
+	"testing"
+)
+
+func TestPolicyTransferRoundTrip(t *testing.T) {
+	source := setupPolicy(t, authPolicy, casbinService.CasbinOptions{})
+	exported, err := source.ExportPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 6 {
+		t.Fatalf("期望导出6条规则，实际 %d", len(exported))
+	}
+	for _, format := range []casbinService.Format{casbinService.FormatCSV, casbinService.FormatJSON, casbinService.FormatYAML} {
+		data, err := casbinService.EncodePolicies(format, exported)
+		if err != nil {
+			t.Fatalf("%s 编码失败: %v", format, err)
+		}
+		decoded, err := casbinService.DecodePolicies(format, data)
+		if err != nil {
+			t.Fatalf("%s 解码失败: %v", format, err)
+		}
+		if !reflect.DeepEqual(decoded, exported) {
+			t.Errorf("%s 往返后规则不一致: %v", format, decoded)
+		}
+	}
+
+	// 导入到数据库存储的实例，替换后导出结果与来源一致
+	target := newInstances(t, 1, casbinService.CasbinOptions{})[0]
+	if _, err := target.AddPolicy("stale", "/api/v1/logs/1", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := target.ImportPolicies(exported, casbinService.ImportReplace)
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if result.Added != len(exported) {
+		t.Errorf("导入结果不符: %+v", result)
+	}
+	imported, err := target.ExportPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported, exported) {
+		t.Errorf("导入后导出的规则不一致: %v", imported)
+	}
+	if result, err = target.ImportPolicies(exported, casbinService.ImportMerge); err != nil || result.Skipped != len(exported) {
+		t.Errorf("重复合并导入应全部跳过: %+v err=%v", result, err)
+	}
+	if _, err := target.ImportPolicies([]casbinService.PolicyLine{{Ptype: "p", Rule: []string{"reader", ""}}}, casbinService.ImportMerge); err == nil {
+		t.Error("字段数不符的规则应被拒绝")
+	}
+}
+
+// TestGormAdapterRemoveExact 删除单条规则时空字段只匹配空值，不能当作通配删除其他规则
+func TestGormAdapterRemoveExact(t *testing.T) {
+	db, err := database.Open(database.DriverMemory, testDBName(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	adapter, err := casbinService.NewGormAdapter(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range [][]string{{"reader", "/api/v1/accounts/:id", "GET"}, {"reader", "/api/v1/accounts/:id", ""}} {
+		if err := adapter.AddPolicy("p", "p", rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func() int64 {
+		var n int64
+		if err := db.Model(&casbinService.CasbinRule{}).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if err := adapter.RemovePolicy("p", "p", []string{"reader", "/api/v1/accounts/:id", ""}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("只应删除空字段的规则，剩余 %d 条", n)
+	}
+	if err := adapter.RemovePolicies("p", "p", [][]string{{"reader", "/api/v1/accounts/:id"}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("批量删除不应匹配字段更多的规则，剩余 %d 条", n)
+	}
+
+	// 按条件删除时空字段仍然匹配任意值
+	if err := adapter.RemoveFilteredPolicy("p", "p", 0, "reader", ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("按条件删除后期望清空，剩余 %d 条", n)
+	}
+}